@@ -0,0 +1,262 @@
+package saml2aws
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/versent/saml2aws/pkg/atomicfile"
+	"github.com/versent/saml2aws/pkg/flock"
+)
+
+// DefaultAccountNameCacheFile the file holding account names resolved by previous logins
+const DefaultAccountNameCacheFile = "~/.saml2aws.d/account-names.json"
+
+var signInAccountNameRegexp = regexp.MustCompile(`^Account:\s*(.+?)\s*\((\d+)\)$`)
+
+// AccountNameResolver looks up the names of the AWS accounts the roles in a SAML assertion belong to
+type AccountNameResolver interface {
+	// ResolveAccountNames returns the names keyed by account id, accounts which can't be resolved are omitted
+	ResolveAccountNames(samlAssertion string, roles []*AWSRole) (map[string]string, error)
+}
+
+// AccountNameRecorder is implemented by resolvers which remember the names found by other resolvers
+type AccountNameRecorder interface {
+	RecordAccountNames(names map[string]string) error
+}
+
+// ResolveAWSAccounts group the roles by account and name each account, the resolvers are tried in
+// order until every account has a name
+func ResolveAWSAccounts(samlAssertion string, roles []*AWSRole, resolvers []AccountNameResolver) ([]*AWSAccount, error) {
+	accountRoles := map[string][]*AWSRole{}
+	accountIDs := []string{}
+
+	for _, role := range roles {
+		accountID, err := AccountIDFromARN(role.RoleARN)
+		if err != nil {
+			return nil, err
+		}
+
+		if role.Name == "" {
+			role.Name = RoleNameFromARN(role.RoleARN)
+		}
+
+		if _, ok := accountRoles[accountID]; !ok {
+			accountIDs = append(accountIDs, accountID)
+		}
+		accountRoles[accountID] = append(accountRoles[accountID], role)
+	}
+
+	sort.Strings(accountIDs)
+
+	names := map[string]string{}
+	found := map[string]string{}
+
+	for _, resolver := range resolvers {
+		unresolved := []*AWSRole{}
+		for _, accountID := range accountIDs {
+			if _, ok := names[accountID]; !ok {
+				unresolved = append(unresolved, accountRoles[accountID]...)
+			}
+		}
+
+		if len(unresolved) == 0 {
+			break
+		}
+
+		resolved, err := resolver.ResolveAccountNames(samlAssertion, unresolved)
+		if err != nil {
+			// fall through to the next resolver, the account id is still a usable name
+			continue
+		}
+
+		// names which came from the user or the cache don't need to be remembered
+		_, recorder := resolver.(AccountNameRecorder)
+		_, static := resolver.(*StaticAccountNameResolver)
+
+		for accountID, name := range resolved {
+			if _, ok := accountRoles[accountID]; !ok || name == "" {
+				continue
+			}
+			names[accountID] = name
+			if !recorder && !static {
+				found[accountID] = name
+			}
+		}
+	}
+
+	if len(found) > 0 {
+		for _, resolver := range resolvers {
+			if recorder, ok := resolver.(AccountNameRecorder); ok {
+				// a failure to record the names only costs another lookup next time
+				_ = recorder.RecordAccountNames(found)
+			}
+		}
+	}
+
+	accounts := make([]*AWSAccount, 0, len(accountIDs))
+
+	for _, accountID := range accountIDs {
+		alias := names[accountID]
+
+		name := "Account: " + accountID
+		if alias != "" {
+			name = "Account: " + alias + " (" + accountID + ")"
+		}
+
+		accounts = append(accounts, &AWSAccount{
+			ID:    accountID,
+			Alias: alias,
+			Name:  name,
+			Roles: accountRoles[accountID],
+		})
+	}
+
+	return accounts, nil
+}
+
+// AccountIDFromARN extract the account id from an IAM ARN
+func AccountIDFromARN(arn string) (string, error) {
+	tokens := strings.SplitN(arn, ":", 6)
+	if len(tokens) != 6 || tokens[0] != "arn" || tokens[4] == "" {
+		return "", errors.Errorf("unable to locate account id in: %s", arn)
+	}
+
+	return tokens[4], nil
+}
+
+// RoleNameFromARN extract the role name from an IAM role ARN, the part after the last slash of the path
+func RoleNameFromARN(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
+
+// StaticAccountNameResolver names accounts from the aliases configured by the user
+type StaticAccountNameResolver struct {
+	Aliases map[string]string
+}
+
+// ResolveAccountNames return the configured aliases for the accounts
+func (sr *StaticAccountNameResolver) ResolveAccountNames(samlAssertion string, roles []*AWSRole) (map[string]string, error) {
+	return lookupAccountNames(sr.Aliases, roles), nil
+}
+
+// FileAccountNameCache names accounts from the results of previous lookups stored on disk
+type FileAccountNameCache struct {
+	Filename string
+}
+
+// NewFileAccountNameCache build the cache, expanding the home directory in the filename
+func NewFileAccountNameCache(filename string) (*FileAccountNameCache, error) {
+	filename, err := homedir.Expand(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileAccountNameCache{Filename: filename}, nil
+}
+
+// ResolveAccountNames return the cached names for the accounts
+func (fc *FileAccountNameCache) ResolveAccountNames(samlAssertion string, roles []*AWSRole) (map[string]string, error) {
+	names, err := fc.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return lookupAccountNames(names, roles), nil
+}
+
+// RecordAccountNames add the names to the cache while holding its lock, so the names recorded by concurrent logins and
+// other saml2aws processes are merged rather than lost
+func (fc *FileAccountNameCache) RecordAccountNames(names map[string]string) error {
+	lock, err := flock.Acquire(fc.Filename + ".lock")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	cached, err := fc.load()
+	if err != nil {
+		cached = map[string]string{}
+	}
+
+	for accountID, name := range names {
+		cached[accountID] = name
+	}
+
+	data, err := json.MarshalIndent(cached, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(fc.Filename, data)
+}
+
+func (fc *FileAccountNameCache) load() (map[string]string, error) {
+	names := map[string]string{}
+
+	data, err := ioutil.ReadFile(fc.Filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return names, nil
+		}
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &names)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decode account name cache")
+	}
+
+	return names, nil
+}
+
+// SignInAccountNameResolver names accounts by posting the assertion to the AWS sign-in page and
+// scraping the account names from the role selection form
+type SignInAccountNameResolver struct{}
+
+// ResolveAccountNames return the account aliases listed on the sign-in page
+func (sr *SignInAccountNameResolver) ResolveAccountNames(samlAssertion string, roles []*AWSRole) (map[string]string, error) {
+	accounts, err := ParseAWSAccounts(samlAssertion)
+	if err != nil {
+		return nil, err
+	}
+
+	return signInAccountNames(accounts), nil
+}
+
+func signInAccountNames(accounts []*AWSAccount) map[string]string {
+	names := map[string]string{}
+
+	for _, account := range accounts {
+		// accounts without an alias are listed by id alone
+		matches := signInAccountNameRegexp.FindStringSubmatch(strings.TrimSpace(account.Name))
+		if matches == nil {
+			continue
+		}
+		names[matches[2]] = matches[1]
+	}
+
+	return names
+}
+
+func lookupAccountNames(aliases map[string]string, roles []*AWSRole) map[string]string {
+	names := map[string]string{}
+
+	for _, role := range roles {
+		accountID, err := AccountIDFromARN(role.RoleARN)
+		if err != nil {
+			continue
+		}
+
+		if name, ok := aliases[accountID]; ok {
+			names[accountID] = name
+		}
+	}
+
+	return names
+}
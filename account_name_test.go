@@ -0,0 +1,162 @@
+package saml2aws
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testAccountNameResolver struct {
+	names map[string]string
+	err   error
+	calls int
+}
+
+func (tr *testAccountNameResolver) ResolveAccountNames(samlAssertion string, roles []*AWSRole) (map[string]string, error) {
+	tr.calls++
+	if tr.err != nil {
+		return nil, tr.err
+	}
+	return lookupAccountNames(tr.names, roles), nil
+}
+
+func testAccountRoles() []*AWSRole {
+	return []*AWSRole{
+		{RoleARN: "arn:aws:iam::000000000002:role/Production", PrincipalARN: "arn:aws:iam::000000000002:saml-provider/test-idp"},
+		{RoleARN: "arn:aws:iam::000000000001:role/Development", PrincipalARN: "arn:aws:iam::000000000001:saml-provider/test-idp"},
+		{RoleARN: "arn:aws:iam::000000000001:role/path/Production", PrincipalARN: "arn:aws:iam::000000000001:saml-provider/test-idp"},
+	}
+}
+
+func TestResolveAWSAccountsOffline(t *testing.T) {
+	signIn := &testAccountNameResolver{err: errors.New("offline")}
+
+	resolvers := []AccountNameResolver{
+		&StaticAccountNameResolver{Aliases: map[string]string{"000000000001": "development", "000000000002": "production"}},
+		signIn,
+	}
+
+	accounts, err := ResolveAWSAccounts("", testAccountRoles(), resolvers)
+	require.Nil(t, err)
+	require.Len(t, accounts, 2)
+
+	assert.Equal(t, 0, signIn.calls)
+
+	assert.Equal(t, "000000000001", accounts[0].ID)
+	assert.Equal(t, "Account: development (000000000001)", accounts[0].Name)
+	require.Len(t, accounts[0].Roles, 2)
+	assert.Equal(t, "Development", accounts[0].Roles[0].Name)
+	assert.Equal(t, "Production", accounts[0].Roles[1].Name)
+	assert.Equal(t, "arn:aws:iam::000000000001:saml-provider/test-idp", accounts[0].Roles[0].PrincipalARN)
+
+	assert.Equal(t, "Account: production (000000000002)", accounts[1].Name)
+}
+
+func TestResolveAWSAccountsFallback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "account-names")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cache, err := NewFileAccountNameCache(filepath.Join(dir, "account-names.json"))
+	require.Nil(t, err)
+
+	failing := &testAccountNameResolver{err: errors.New("blocked by proxy")}
+	remote := &testAccountNameResolver{names: map[string]string{"000000000002": "production"}}
+
+	resolvers := []AccountNameResolver{
+		&StaticAccountNameResolver{Aliases: map[string]string{"000000000001": "development"}},
+		cache,
+		failing,
+		remote,
+	}
+
+	accounts, err := ResolveAWSAccounts("", testAccountRoles(), resolvers)
+	require.Nil(t, err)
+	require.Len(t, accounts, 2)
+	assert.Equal(t, "Account: development (000000000001)", accounts[0].Name)
+	assert.Equal(t, "Account: production (000000000002)", accounts[1].Name)
+	assert.Equal(t, 1, failing.calls)
+	assert.Equal(t, 1, remote.calls)
+
+	// only the name found remotely is cached, the next lookup doesn't need the remote resolver
+	cached, err := cache.load()
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{"000000000002": "production"}, cached)
+
+	accounts, err = ResolveAWSAccounts("", testAccountRoles(), resolvers)
+	require.Nil(t, err)
+	assert.Equal(t, "Account: production (000000000002)", accounts[1].Name)
+	assert.Equal(t, 1, remote.calls)
+}
+
+func TestRecordAccountNamesConcurrently(t *testing.T) {
+	dir, err := ioutil.TempDir("", "account-names")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "account-names.json")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 25; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			// each cache stands in for another saml2aws process
+			cache, err := NewFileAccountNameCache(filename)
+			assert.Nil(t, err)
+			assert.Nil(t, cache.RecordAccountNames(map[string]string{fmt.Sprintf("%012d", i): fmt.Sprintf("account-%d", i)}))
+		}(i)
+	}
+	wg.Wait()
+
+	cache, err := NewFileAccountNameCache(filename)
+	require.Nil(t, err)
+
+	names, err := cache.load()
+	require.Nil(t, err)
+	assert.Len(t, names, 25)
+
+	info, err := os.Stat(filename)
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestResolveAWSAccountsUnresolved(t *testing.T) {
+	accounts, err := ResolveAWSAccounts("", testAccountRoles(), nil)
+	require.Nil(t, err)
+	require.Len(t, accounts, 2)
+	assert.Equal(t, "Account: 000000000001", accounts[0].Name)
+	assert.Equal(t, "Account: 000000000002", accounts[1].Name)
+}
+
+func TestSignInAccountNames(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/saml.html")
+	require.Nil(t, err)
+
+	accounts, err := ExtractAWSAccounts(data)
+	require.Nil(t, err)
+
+	assert.Equal(t, map[string]string{"000000000001": "account-alias"}, signInAccountNames(accounts))
+}
+
+func TestAccountIDFromARN(t *testing.T) {
+	accountID, err := AccountIDFromARN("arn:aws:iam::000000000001:role/Development")
+	require.Nil(t, err)
+	assert.Equal(t, "000000000001", accountID)
+
+	_, err = AccountIDFromARN("Development")
+	assert.Error(t, err)
+}
+
+func TestRoleNameFromARN(t *testing.T) {
+	assert.Equal(t, "Development", RoleNameFromARN("arn:aws:iam::000000000001:role/Development"))
+	assert.Equal(t, "Admin", RoleNameFromARN("arn:aws:iam::000000000001:role/team/Admin"))
+}
@@ -0,0 +1,296 @@
+package saml2aws
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/pkg/errors"
+)
+
+const (
+	// RoleAttributeName the SAML attribute which lists the roles available to the user
+	RoleAttributeName = "https://aws.amazon.com/SAML/Attributes/Role"
+
+	// SessionDurationAttributeName the SAML attribute which provides the maximum session duration in seconds
+	SessionDurationAttributeName = "https://aws.amazon.com/SAML/Attributes/SessionDuration"
+
+	// RoleSessionNameAttributeName the SAML attribute which sets the name of the assumed role session
+	RoleSessionNameAttributeName = "https://aws.amazon.com/SAML/Attributes/RoleSessionName"
+
+	// SourceIdentityAttributeName the SAML attribute which sets the source identity of the assumed role session
+	SourceIdentityAttributeName = "https://aws.amazon.com/SAML/Attributes/SourceIdentity"
+
+	// PrincipalTagAttributePrefix the prefix of the SAML attributes which are passed as session tags
+	PrincipalTagAttributePrefix = "https://aws.amazon.com/SAML/Attributes/PrincipalTag:"
+
+	// TransitiveTagKeysAttributeName the SAML attribute which lists the session tags which persist through role chaining
+	TransitiveTagKeysAttributeName = "https://aws.amazon.com/SAML/Attributes/TransitiveTagKeys"
+
+	issuerTag                  = "Issuer"
+	subjectTag                 = "Subject"
+	nameIDTag                  = "NameID"
+	subjectConfirmationTag     = "SubjectConfirmation"
+	subjectConfirmationDataTag = "SubjectConfirmationData"
+	conditionsTag              = "Conditions"
+	audienceTag                = "Audience"
+	authnStatementTag          = "AuthnStatement"
+	authnContextClassRefTag    = "AuthnContextClassRef"
+)
+
+// SAMLAssertion the decoded contents of a SAML assertion
+type SAMLAssertion struct {
+	ID                string              `json:"id,omitempty"`
+	Issuer            string              `json:"issuer,omitempty"`
+	IssueInstant      time.Time           `json:"issueInstant"`
+	Subject           SAMLSubject         `json:"subject"`
+	Conditions        SAMLConditions      `json:"conditions"`
+	AuthnStatement    *SAMLAuthnStatement `json:"authnStatement,omitempty"`
+	Attributes        []*SAMLAttribute    `json:"attributes"`
+	Roles             []*AWSRole          `json:"roles"`
+	SessionDuration   int64               `json:"sessionDuration,omitempty"`
+	RoleSessionName   string              `json:"roleSessionName,omitempty"`
+	SourceIdentity    string              `json:"sourceIdentity,omitempty"`
+	PrincipalTags     map[string]string   `json:"principalTags,omitempty"`
+	TransitiveTagKeys []string            `json:"transitiveTagKeys,omitempty"`
+}
+
+// SAMLSubject the subject of the assertion
+type SAMLSubject struct {
+	NameID       string    `json:"nameID,omitempty"`
+	NameIDFormat string    `json:"nameIDFormat,omitempty"`
+	Recipient    string    `json:"recipient,omitempty"`
+	NotOnOrAfter time.Time `json:"notOnOrAfter"`
+}
+
+// SAMLConditions the validity window and audience restrictions of the assertion
+type SAMLConditions struct {
+	NotBefore    time.Time `json:"notBefore"`
+	NotOnOrAfter time.Time `json:"notOnOrAfter"`
+	Audiences    []string  `json:"audiences"`
+}
+
+// SAMLAuthnStatement describes how and when the user authenticated to the IdP
+type SAMLAuthnStatement struct {
+	AuthnInstant         time.Time `json:"authnInstant"`
+	SessionIndex         string    `json:"sessionIndex,omitempty"`
+	SessionNotOnOrAfter  time.Time `json:"sessionNotOnOrAfter"`
+	AuthnContextClassRef string    `json:"authnContextClassRef,omitempty"`
+}
+
+// SAMLAttribute a single attribute from the attribute statement
+type SAMLAttribute struct {
+	Name         string   `json:"name"`
+	FriendlyName string   `json:"friendlyName,omitempty"`
+	Values       []string `json:"values"`
+}
+
+// Attribute return the named attribute or nil if it isn't present
+func (sa *SAMLAssertion) Attribute(name string) *SAMLAttribute {
+	for _, attribute := range sa.Attributes {
+		if attribute.Name == name {
+			return attribute
+		}
+	}
+	return nil
+}
+
+// ParseSAMLAssertion decode the assertion in a SAML response document into a structured form
+func ParseSAMLAssertion(data []byte) (*SAMLAssertion, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return nil, err
+	}
+
+	assertionElement := doc.FindElement(".//" + assertionTag)
+	if assertionElement == nil {
+		if doc.FindElement(".//"+encryptedAssertionTag) != nil {
+			return nil, ErrEncryptedAssertion
+		}
+		return nil, ErrMissingAssertion
+	}
+
+	space := assertionElement.Space
+
+	assertion := &SAMLAssertion{
+		ID:         assertionElement.SelectAttrValue("ID", ""),
+		Attributes: []*SAMLAttribute{},
+		Roles:      []*AWSRole{},
+	}
+
+	var err error
+
+	assertion.IssueInstant, err = parseTimeAttr(assertionElement, "IssueInstant")
+	if err != nil {
+		return nil, err
+	}
+
+	if issuer := assertionElement.FindElement(childPath(space, issuerTag)); issuer != nil {
+		assertion.Issuer = strings.TrimSpace(issuer.Text())
+	}
+
+	if subject := assertionElement.FindElement(childPath(space, subjectTag)); subject != nil {
+		if nameID := subject.FindElement(childPath(space, nameIDTag)); nameID != nil {
+			assertion.Subject.NameID = strings.TrimSpace(nameID.Text())
+			assertion.Subject.NameIDFormat = nameID.SelectAttrValue("Format", "")
+		}
+
+		if confirmationData := subject.FindElement(childPath(space, subjectConfirmationTag) + "/" + subjectConfirmationDataTag); confirmationData != nil {
+			assertion.Subject.Recipient = confirmationData.SelectAttrValue("Recipient", "")
+			assertion.Subject.NotOnOrAfter, err = parseTimeAttr(confirmationData, "NotOnOrAfter")
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if conditions := assertionElement.FindElement(childPath(space, conditionsTag)); conditions != nil {
+		assertion.Conditions.NotBefore, err = parseTimeAttr(conditions, "NotBefore")
+		if err != nil {
+			return nil, err
+		}
+
+		assertion.Conditions.NotOnOrAfter, err = parseTimeAttr(conditions, "NotOnOrAfter")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, audience := range conditions.FindElements(".//" + audienceTag) {
+			assertion.Conditions.Audiences = append(assertion.Conditions.Audiences, strings.TrimSpace(audience.Text()))
+		}
+	}
+
+	if authnStatement := assertionElement.FindElement(childPath(space, authnStatementTag)); authnStatement != nil {
+		assertion.AuthnStatement = &SAMLAuthnStatement{
+			SessionIndex: authnStatement.SelectAttrValue("SessionIndex", ""),
+		}
+
+		assertion.AuthnStatement.AuthnInstant, err = parseTimeAttr(authnStatement, "AuthnInstant")
+		if err != nil {
+			return nil, err
+		}
+
+		assertion.AuthnStatement.SessionNotOnOrAfter, err = parseTimeAttr(authnStatement, "SessionNotOnOrAfter")
+		if err != nil {
+			return nil, err
+		}
+
+		if classRef := authnStatement.FindElement(".//" + authnContextClassRefTag); classRef != nil {
+			assertion.AuthnStatement.AuthnContextClassRef = strings.TrimSpace(classRef.Text())
+		}
+	}
+
+	if attributeStatement := assertionElement.FindElement(childPath(space, attributeStatementTag)); attributeStatement != nil {
+		for _, attributeElement := range attributeStatement.FindElements(childPath(space, attributeTag)) {
+			attribute := &SAMLAttribute{
+				Name:         attributeElement.SelectAttrValue("Name", ""),
+				FriendlyName: attributeElement.SelectAttrValue("FriendlyName", ""),
+				Values:       []string{},
+			}
+
+			for _, attrValue := range attributeElement.FindElements(childPath(space, attributeValueTag)) {
+				attribute.Values = append(attribute.Values, strings.TrimSpace(attrValue.Text()))
+			}
+
+			assertion.Attributes = append(assertion.Attributes, attribute)
+		}
+	}
+
+	if roles := assertion.Attribute(RoleAttributeName); roles != nil {
+		assertion.Roles, err = ParseAWSRoles(roles.Values)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing aws roles")
+		}
+	}
+
+	if sessionDuration := assertion.Attribute(SessionDurationAttributeName); sessionDuration != nil && len(sessionDuration.Values) > 0 {
+		assertion.SessionDuration, err = strconv.ParseInt(sessionDuration.Values[0], 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing session duration")
+		}
+	}
+
+	if roleSessionName := assertion.Attribute(RoleSessionNameAttributeName); roleSessionName != nil && len(roleSessionName.Values) > 0 {
+		assertion.RoleSessionName = roleSessionName.Values[0]
+	}
+
+	if sourceIdentity := assertion.Attribute(SourceIdentityAttributeName); sourceIdentity != nil && len(sourceIdentity.Values) > 0 {
+		assertion.SourceIdentity = sourceIdentity.Values[0]
+	}
+
+	for _, attribute := range assertion.Attributes {
+		if !strings.HasPrefix(attribute.Name, PrincipalTagAttributePrefix) || len(attribute.Values) == 0 {
+			continue
+		}
+
+		if assertion.PrincipalTags == nil {
+			assertion.PrincipalTags = map[string]string{}
+		}
+
+		// AWS only accepts a single value for each session tag
+		assertion.PrincipalTags[strings.TrimPrefix(attribute.Name, PrincipalTagAttributePrefix)] = attribute.Values[0]
+	}
+
+	if transitiveTagKeys := assertion.Attribute(TransitiveTagKeysAttributeName); transitiveTagKeys != nil {
+		assertion.TransitiveTagKeys = transitiveTagKeys.Values
+	}
+
+	return assertion, nil
+}
+
+// Expires return the earliest time at which the assertion is no longer accepted, zero if it doesn't expire
+func (sa *SAMLAssertion) Expires() time.Time {
+	expires := sa.Conditions.NotOnOrAfter
+
+	if !sa.Subject.NotOnOrAfter.IsZero() && (expires.IsZero() || sa.Subject.NotOnOrAfter.Before(expires)) {
+		expires = sa.Subject.NotOnOrAfter
+	}
+
+	return expires
+}
+
+// SessionTags return the principal tags sorted by key, marking those which are transitive
+func (sa *SAMLAssertion) SessionTags() []string {
+	keys := make([]string, 0, len(sa.PrincipalTags))
+	for key := range sa.PrincipalTags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tags := make([]string, 0, len(keys))
+	for _, key := range keys {
+		tag := fmt.Sprintf("%s=%s", key, sa.PrincipalTags[key])
+		if sa.isTransitive(key) {
+			tag += " (transitive)"
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
+
+func (sa *SAMLAssertion) isTransitive(key string) bool {
+	for _, transitive := range sa.TransitiveTagKeys {
+		if transitive == key {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTimeAttr(el *etree.Element, key string) (time.Time, error) {
+	value := el.SelectAttrValue(key, "")
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "invalid %s attribute on %s element", key, el.Tag)
+	}
+
+	return t, nil
+}
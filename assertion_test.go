@@ -0,0 +1,52 @@
+package saml2aws
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSAMLAssertion(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/assertion.xml")
+	require.Nil(t, err)
+
+	assertion, err := ParseSAMLAssertion(data)
+	require.Nil(t, err)
+
+	require.Equal(t, "_f85be5f5-584c-4711-8c9d-5b13c4c49f89", assertion.ID)
+	require.Equal(t, "http://id.example.com/adfs/services/trust", assertion.Issuer)
+	require.Equal(t, "EXAMPLE\\wolfeidau", assertion.Subject.NameID)
+	require.Equal(t, "https://signin.aws.amazon.com/saml", assertion.Subject.Recipient)
+	require.Equal(t, []string{"urn:amazon:webservices"}, assertion.Conditions.Audiences)
+	require.Equal(t, time.Date(2016, 9, 10, 3, 54, 39, 371000000, time.UTC), assertion.Conditions.NotOnOrAfter)
+	require.NotNil(t, assertion.AuthnStatement)
+	require.Equal(t, "urn:oasis:names:tc:SAML:2.0:ac:classes:PasswordProtectedTransport", assertion.AuthnStatement.AuthnContextClassRef)
+	require.NotNil(t, assertion.Attribute("https://aws.amazon.com/SAML/Attributes/RoleSessionName"))
+	require.Len(t, assertion.Roles, 2)
+	require.Equal(t, int64(28800), assertion.SessionDuration)
+	require.Equal(t, "wolfeidau@example.com", assertion.RoleSessionName)
+	require.Empty(t, assertion.SourceIdentity)
+	require.Empty(t, assertion.PrincipalTags)
+	require.Equal(t, time.Date(2016, 9, 10, 2, 59, 39, 387000000, time.UTC), assertion.Expires())
+}
+
+func TestParseSAMLAssertionMissing(t *testing.T) {
+	_, err := ParseSAMLAssertion([]byte(`<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol"></samlp:Response>`))
+	require.Equal(t, ErrMissingAssertion, err)
+}
+
+func TestParseSAMLAssertionSessionAttributes(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/assertion_session_tags.xml")
+	require.Nil(t, err)
+
+	assertion, err := ParseSAMLAssertion(data)
+	require.Nil(t, err)
+
+	require.Equal(t, "wolfeidau@example.com", assertion.RoleSessionName)
+	require.Equal(t, "wolfeidau", assertion.SourceIdentity)
+	require.Equal(t, map[string]string{"CostCenter": "12345", "Project": "cloudops"}, assertion.PrincipalTags)
+	require.Equal(t, []string{"Project"}, assertion.TransitiveTagKeys)
+	require.Equal(t, []string{"CostCenter=12345", "Project=cloudops (transitive)"}, assertion.SessionTags())
+}
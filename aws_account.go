@@ -2,47 +2,88 @@ package saml2aws
 
 import (
 	"bytes"
+	b64 "encoding/base64"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
-
-	"fmt"
-        "strings"
-        b64 "encoding/base64"
-
+	"strings"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/pkg/errors"
+	"github.com/versent/saml2aws/pkg/partition"
 )
 
-var awsURL = "https://signin.aws.amazon.com/saml"
-
 // AWSAccount holds the AWS account name and roles
 type AWSAccount struct {
+	ID    string
+	Alias string
 	Name  string
 	Roles []*AWSRole
 }
 
-// ParseAWSAccounts extract the aws accounts from the saml assertion
+// ParseAWSAccounts extract the aws accounts from the saml assertion, the assertion is posted to the
+// sign-in page of each partition its roles belong to
 func ParseAWSAccounts(samlAssertion string) ([]*AWSAccount, error) {
-        decSamlAssertion, _ := b64.StdEncoding.DecodeString(samlAssertion)
-        if strings.Contains(string(decSamlAssertion), "signin.amazonaws.cn") {
-            fmt.Println("trying to login AWS China")
-            awsURL = "https://signin.amazonaws.cn/saml"
-        }
+	accounts := []*AWSAccount{}
 
+	for _, p := range assertionPartitions(samlAssertion) {
+		res, err := http.PostForm(p.SignInURL, url.Values{"SAMLResponse": {samlAssertion}})
+		if err != nil {
+			return nil, errors.Wrap(err, "error retrieving AWS login form")
+		}
 
-	res, err := http.PostForm(awsURL, url.Values{"SAMLResponse": {samlAssertion}})
-	if err != nil {
-		return nil, errors.Wrap(err, "error retrieving AWS login form")
+		data, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "error retrieving AWS login body")
+		}
+
+		partitionAccounts, err := ExtractAWSAccounts(data)
+		if err != nil {
+			return nil, err
+		}
+
+		accounts = append(accounts, partitionAccounts...)
 	}
 
-	data, err := ioutil.ReadAll(res.Body)
+	return accounts, nil
+}
+
+// assertionPartitions list the partitions of the roles in the saml assertion, in the order they first appear
+func assertionPartitions(samlAssertion string) []*partition.Partition {
+	partitions := []*partition.Partition{}
+
+	decSamlAssertion, err := b64.StdEncoding.DecodeString(samlAssertion)
 	if err != nil {
-		return nil, errors.Wrap(err, "error retrieving AWS login body")
+		return []*partition.Partition{partition.AWS}
+	}
+
+	roles := []*AWSRole{}
+	if roleStrings, err := ExtractAwsRoles(decSamlAssertion); err == nil {
+		roles, _ = ParseAWSRoles(roleStrings)
+	}
+
+	seen := map[*partition.Partition]bool{}
+	for _, role := range roles {
+		p, err := partition.FromRoleARNs(role.RoleARN, role.PrincipalARN)
+		if err != nil || seen[p] {
+			continue
+		}
+		seen[p] = true
+		partitions = append(partitions, p)
+	}
+
+	if len(partitions) > 0 {
+		return partitions
+	}
+
+	// fall back to the audience of the assertion when the roles don't name a known partition
+	if strings.Contains(string(decSamlAssertion), "signin.amazonaws.cn") {
+		return []*partition.Partition{partition.AWSCN}
 	}
 
-	return ExtractAWSAccounts(data)
+	return []*partition.Partition{partition.AWS}
 }
 
 // ExtractAWSAccounts extract the accounts from the AWS html page
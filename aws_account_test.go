@@ -1,10 +1,12 @@
 package saml2aws
 
 import (
+	b64 "encoding/base64"
 	"io/ioutil"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/versent/saml2aws/pkg/partition"
 )
 
 func TestExtractAWSAccounts(t *testing.T) {
@@ -76,3 +78,22 @@ func TestLocateRole(t *testing.T) {
 
 	assert.Equal(t, "arn:aws:iam::000000000001:role/Development", role.RoleARN)
 }
+
+func TestAssertionPartitions(t *testing.T) {
+	assertion := `<Response><Assertion><AttributeStatement>
+<Attribute Name="https://aws.amazon.com/SAML/Attributes/Role">
+<AttributeValue>arn:aws-us-gov:iam::000000000001:role/Development,arn:aws-us-gov:iam::000000000001:saml-provider/ADFS</AttributeValue>
+<AttributeValue>arn:aws:iam::000000000002:role/Development,arn:aws:iam::000000000002:saml-provider/ADFS</AttributeValue>
+<AttributeValue>arn:aws-us-gov:iam::000000000003:role/Production,arn:aws-us-gov:iam::000000000003:saml-provider/ADFS</AttributeValue>
+</Attribute>
+</AttributeStatement></Assertion></Response>`
+
+	partitions := assertionPartitions(b64.StdEncoding.EncodeToString([]byte(assertion)))
+	assert.Equal(t, []*partition.Partition{partition.AWSUSGov, partition.AWS}, partitions)
+
+	partitions = assertionPartitions(b64.StdEncoding.EncodeToString([]byte(`<Response Destination="https://signin.amazonaws.cn/saml"/>`)))
+	assert.Equal(t, []*partition.Partition{partition.AWSCN}, partitions)
+
+	partitions = assertionPartitions("not base64")
+	assert.Equal(t, []*partition.Partition{partition.AWS}, partitions)
+}
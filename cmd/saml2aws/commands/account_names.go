@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/versent/saml2aws"
+	"github.com/versent/saml2aws/pkg/cfg"
+	"github.com/versent/saml2aws/pkg/partition"
+)
+
+// buildAccountNameResolvers build the account name resolvers in the order configured on the account
+func buildAccountNameResolvers(account *cfg.IDPAccount) ([]saml2aws.AccountNameResolver, error) {
+	order := account.AccountNameResolvers
+	if order == "" {
+		order = cfg.DefaultAccountNameResolvers
+	}
+
+	resolvers := []saml2aws.AccountNameResolver{}
+
+	for _, name := range strings.Split(order, ",") {
+		switch strings.TrimSpace(name) {
+		case "aliases":
+			cfgm, err := cfg.NewConfigManager(cfg.DefaultConfigPath)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to load configuration")
+			}
+
+			aliases, err := cfgm.LoadAccountAliases()
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to load account aliases")
+			}
+
+			resolvers = append(resolvers, &saml2aws.StaticAccountNameResolver{Aliases: aliases})
+		case "cache":
+			cache, err := saml2aws.NewFileAccountNameCache(saml2aws.DefaultAccountNameCacheFile)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to locate account name cache")
+			}
+
+			resolvers = append(resolvers, cache)
+		case "signin":
+			resolvers = append(resolvers, &saml2aws.SignInAccountNameResolver{})
+		case "iam":
+			resolvers = append(resolvers, &iamAccountNameResolver{account: account})
+		case "":
+		default:
+			return nil, errors.Errorf("unknown account name resolver %s, expected aliases, cache, signin or iam", name)
+		}
+	}
+
+	return resolvers, nil
+}
+
+// iamAccountNameResolver names accounts by assuming a role in each account and calling iam:ListAccountAliases
+type iamAccountNameResolver struct {
+	account *cfg.IDPAccount
+}
+
+func (ir *iamAccountNameResolver) ResolveAccountNames(samlAssertion string, roles []*saml2aws.AWSRole) (map[string]string, error) {
+	logger := logrus.WithField("resolver", "iam")
+
+	names := map[string]string{}
+
+	for _, role := range roles {
+		accountID, err := saml2aws.AccountIDFromARN(role.RoleARN)
+		if err != nil {
+			continue
+		}
+
+		if _, ok := names[accountID]; ok {
+			continue
+		}
+
+		awsPartition, err := partition.FromRoleARNs(role.RoleARN, role.PrincipalARN)
+		if err != nil {
+			continue
+		}
+
+		sess, svc, err := newPartitionSession(awsPartition)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := svc.AssumeRoleWithSAML(&sts.AssumeRoleWithSAMLInput{
+			PrincipalArn:    aws.String(role.PrincipalARN),
+			RoleArn:         aws.String(role.RoleARN),
+			SAMLAssertion:   aws.String(samlAssertion),
+			DurationSeconds: aws.Int64(900), // the shortest session STS allows
+		})
+		if err != nil {
+			logger.WithError(err).WithField("role", role.RoleARN).Debug("unable to assume role")
+			continue
+		}
+
+		creds := credentials.NewStaticCredentials(
+			aws.StringValue(resp.Credentials.AccessKeyId),
+			aws.StringValue(resp.Credentials.SecretAccessKey),
+			aws.StringValue(resp.Credentials.SessionToken),
+		)
+
+		aliases, err := iam.New(sess, aws.NewConfig().WithCredentials(creds)).ListAccountAliases(&iam.ListAccountAliasesInput{})
+		if err != nil {
+			logger.WithError(err).WithField("role", role.RoleARN).Debug("unable to list account aliases")
+			continue
+		}
+
+		if len(aliases.AccountAliases) > 0 {
+			names[accountID] = aws.StringValue(aliases.AccountAliases[0])
+		}
+	}
+
+	return names, nil
+}
@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/versent/saml2aws"
+	"github.com/versent/saml2aws/pkg/flags"
+)
+
+// Assertion decode a SAML response and print the contents of the assertion
+func Assertion(loginFlags *flags.LoginExecFlags, inputFile string, format string) error {
+
+	var data []byte
+
+	if inputFile != "" {
+		input, err := readSAMLResponse(inputFile)
+		if err != nil {
+			return errors.Wrap(err, "error reading saml response")
+		}
+
+		data, err = decodeSAMLResponse(input)
+		if err != nil {
+			return errors.Wrap(err, "error decoding saml response")
+		}
+	} else {
+		account, err := buildIdpAccount(loginFlags)
+		if err != nil {
+			return errors.Wrap(err, "error building login details")
+		}
+
+		samlAssertion, err := authenticate(os.Stdout, account, loginFlags)
+		if err != nil {
+			return err
+		}
+
+		data, err = decodeSAMLAssertion(samlAssertion, account)
+		if err != nil {
+			return err
+		}
+	}
+
+	assertion, err := saml2aws.ParseSAMLAssertion(data)
+	if err != nil {
+		return errors.Wrap(err, "error parsing saml assertion")
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(assertion)
+	}
+
+	printAssertion(assertion)
+
+	return nil
+}
+
+func readSAMLResponse(inputFile string) (string, error) {
+	if inputFile == "-" {
+		data, err := ioutil.ReadAll(os.Stdin)
+		return string(data), err
+	}
+
+	data, err := ioutil.ReadFile(inputFile)
+	return string(data), err
+}
+
+// decodeSAMLResponse accepts the SAMLResponse as posted by the browser, either raw, url encoded or as
+// a form parameter, along with plain XML and returns the XML document
+func decodeSAMLResponse(input string) ([]byte, error) {
+	input = strings.TrimSpace(input)
+	input = strings.TrimPrefix(input, "SAMLResponse=")
+
+	if strings.HasPrefix(input, "<") {
+		return []byte(input), nil
+	}
+
+	if strings.Contains(input, "%") {
+		unescaped, err := url.QueryUnescape(input)
+		if err != nil {
+			return nil, err
+		}
+		input = unescaped
+	}
+
+	// base64 content copied out of a browser or log file is often wrapped
+	input = strings.Join(strings.Fields(input), "")
+
+	return base64.StdEncoding.DecodeString(input)
+}
+
+func printAssertion(assertion *saml2aws.SAMLAssertion) {
+	printField("Issuer", assertion.Issuer)
+	printField("ID", assertion.ID)
+	printField("Issue Instant", formatAssertionTime(assertion.IssueInstant))
+	fmt.Println("")
+
+	fmt.Println("Subject")
+	printField("  Name ID", assertion.Subject.NameID)
+	printField("  Format", assertion.Subject.NameIDFormat)
+	printField("  Recipient", assertion.Subject.Recipient)
+	printField("  Not On Or After", formatAssertionTime(assertion.Subject.NotOnOrAfter))
+	fmt.Println("")
+
+	fmt.Println("Conditions")
+	printField("  Not Before", formatAssertionTime(assertion.Conditions.NotBefore))
+	printField("  Not On Or After", formatAssertionTime(assertion.Conditions.NotOnOrAfter))
+	printField("  Audience", strings.Join(assertion.Conditions.Audiences, ", "))
+	fmt.Println("")
+
+	if assertion.AuthnStatement != nil {
+		fmt.Println("Authentication")
+		printField("  Authn Instant", formatAssertionTime(assertion.AuthnStatement.AuthnInstant))
+		printField("  Session Index", assertion.AuthnStatement.SessionIndex)
+		printField("  Session Expires", formatAssertionTime(assertion.AuthnStatement.SessionNotOnOrAfter))
+		printField("  Context", assertion.AuthnStatement.AuthnContextClassRef)
+		fmt.Println("")
+	}
+
+	fmt.Println("Attributes")
+	for _, attribute := range assertion.Attributes {
+		name := attribute.Name
+		if attribute.FriendlyName != "" {
+			name = fmt.Sprintf("%s (%s)", attribute.Name, attribute.FriendlyName)
+		}
+		fmt.Printf("  %s\n", name)
+		for _, value := range attribute.Values {
+			fmt.Printf("    %s\n", value)
+		}
+	}
+	fmt.Println("")
+
+	fmt.Println("AWS Roles")
+	if len(assertion.Roles) == 0 {
+		fmt.Println("  none")
+	}
+	for _, role := range assertion.Roles {
+		fmt.Printf("  %s\n", role.RoleARN)
+		fmt.Printf("    principal: %s\n", role.PrincipalARN)
+	}
+	fmt.Println("")
+
+	printField("Role Session Name", assertion.RoleSessionName)
+	printField("Source Identity", assertion.SourceIdentity)
+	if assertion.SessionDuration > 0 {
+		duration := time.Duration(assertion.SessionDuration) * time.Second
+		printField("Session Duration", fmt.Sprintf("%d (%s)", assertion.SessionDuration, duration))
+	}
+
+	if len(assertion.PrincipalTags) > 0 {
+		fmt.Println("")
+		fmt.Println("Session Tags")
+		for _, tag := range assertion.SessionTags() {
+			fmt.Printf("  %s\n", tag)
+		}
+	}
+}
+
+func printField(name, value string) {
+	if value == "" {
+		return
+	}
+	fmt.Printf("%-20s %s\n", name+":", value)
+}
+
+func formatAssertionTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	if time.Now().After(t) {
+		return fmt.Sprintf("%s (%s ago)", t.Format(time.RFC3339), time.Since(t).Round(time.Second))
+	}
+
+	return fmt.Sprintf("%s (in %s)", t.Format(time.RFC3339), time.Until(t).Round(time.Second))
+}
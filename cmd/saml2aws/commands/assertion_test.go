@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeSAMLResponse(t *testing.T) {
+	xml := `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol"/>`
+	encoded := base64.StdEncoding.EncodeToString([]byte(xml))
+
+	inputs := map[string]string{
+		"xml":     "  " + xml + "\n",
+		"base64":  encoded,
+		"wrapped": encoded[:20] + "\n" + encoded[20:] + "\n",
+		"form":    "SAMLResponse=" + url.QueryEscape(encoded),
+	}
+
+	for name, input := range inputs {
+		data, err := decodeSAMLResponse(input)
+		assert.Nil(t, err, name)
+		assert.Equal(t, xml, string(data), name)
+	}
+
+	_, err := decodeSAMLResponse("not!base64")
+	assert.Error(t, err)
+}
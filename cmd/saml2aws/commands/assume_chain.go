@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awscredentials "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/pkg/errors"
+	"github.com/versent/saml2aws/pkg/awsconfig"
+	"github.com/versent/saml2aws/pkg/cfg"
+	"github.com/versent/saml2aws/pkg/partition"
+)
+
+// defaultChainSessionDuration AWS limits sessions from role chaining to an hour
+const defaultChainSessionDuration = 3600
+
+// assumeChain assume each role of the assume_chain in turn starting from the SAML credentials, returning the
+// credentials of the last role, the credentials of hops naming a profile are saved along the way when save is true
+func assumeChain(out io.Writer, account *cfg.IDPAccount, awsCreds *awsconfig.AWSCredentials, save bool) (*awsconfig.AWSCredentials, error) {
+	hops, err := account.ParseAssumeChain()
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing assume_chain")
+	}
+
+	for _, hop := range hops {
+		awsCreds, err = assumeChainHop(hop, awsCreds)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error assuming role %s", hop.RoleARN)
+		}
+
+		if save && hop.Profile != "" {
+			err = awsconfig.NewSharedCredentials(hop.Profile).Save(awsCreds)
+			if err != nil {
+				return nil, errors.Wrap(err, "error saving credentials")
+			}
+			fmt.Fprintln(out, "Saved credentials for", awsCreds.PrincipalARN, "to profile", hop.Profile)
+		}
+	}
+
+	return awsCreds, nil
+}
+
+// assumeChainHop assume the role of the hop with the credentials of the previous one
+func assumeChainHop(hop cfg.ChainHop, awsCreds *awsconfig.AWSCredentials) (*awsconfig.AWSCredentials, error) {
+	awsPartition, err := partition.FromARN(hop.RoleARN)
+	if err != nil {
+		return nil, err
+	}
+
+	region := awsPartition.ResolveRegion()
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion(region).
+		WithCredentials(awscredentials.NewStaticCredentials(awsCreds.AWSAccessKey, awsCreds.AWSSecretKey, awsCreds.AWSSessionToken)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create session")
+	}
+
+	svc := sts.New(sess, aws.NewConfig().WithEndpoint(awsPartition.STSEndpoint(region)))
+
+	duration := hop.SessionDuration
+	if duration == 0 {
+		duration = defaultChainSessionDuration
+	}
+
+	params := &sts.AssumeRoleInput{
+		RoleArn:         aws.String(hop.RoleARN),
+		RoleSessionName: aws.String(chainSessionName(hop, awsCreds)),
+		DurationSeconds: aws.Int64(int64(duration)),
+	}
+	if hop.ExternalID != "" {
+		params.ExternalId = aws.String(hop.ExternalID)
+	}
+
+	resp, err := svc.AssumeRole(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &awsconfig.AWSCredentials{
+		AWSAccessKey:     aws.StringValue(resp.Credentials.AccessKeyId),
+		AWSSecretKey:     aws.StringValue(resp.Credentials.SecretAccessKey),
+		AWSSessionToken:  aws.StringValue(resp.Credentials.SessionToken),
+		AWSSecurityToken: aws.StringValue(resp.Credentials.SessionToken),
+		PrincipalARN:     aws.StringValue(resp.AssumedRoleUser.Arn),
+		Expires:          resp.Credentials.Expiration.Local(),
+		RoleSessionName:  awsCreds.RoleSessionName,
+		SourceIdentity:   awsCreds.SourceIdentity,
+		IdpAccount:       awsCreds.IdpAccount,
+	}, nil
+}
+
+// chainSessionName name the session of the hop, by default carrying on the session name of the previous role so
+// CloudTrail still shows who logged in
+func chainSessionName(hop cfg.ChainHop, awsCreds *awsconfig.AWSCredentials) string {
+	if hop.SessionName != "" {
+		return hop.SessionName
+	}
+
+	if awsCreds.RoleSessionName != "" {
+		return awsCreds.RoleSessionName
+	}
+
+	// an assumed role ARN ends with the session name, arn:aws:sts::000000000001:assumed-role/Role/session
+	parts := strings.Split(awsCreds.PrincipalARN, "/")
+	if len(parts) == 3 && parts[2] != "" {
+		return parts[2]
+	}
+
+	return "saml2aws"
+}
@@ -0,0 +1,21 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/versent/saml2aws/pkg/awsconfig"
+	"github.com/versent/saml2aws/pkg/cfg"
+)
+
+func TestChainSessionName(t *testing.T) {
+	awsCreds := &awsconfig.AWSCredentials{PrincipalARN: "arn:aws:sts::000000000001:assumed-role/Development/wolfeidau"}
+
+	assert.Equal(t, "ops", chainSessionName(cfg.ChainHop{SessionName: "ops"}, awsCreds))
+	assert.Equal(t, "wolfeidau", chainSessionName(cfg.ChainHop{}, awsCreds))
+
+	awsCreds.RoleSessionName = "mark@wolfe.id.au"
+	assert.Equal(t, "mark@wolfe.id.au", chainSessionName(cfg.ChainHop{}, awsCreds))
+
+	assert.Equal(t, "saml2aws", chainSessionName(cfg.ChainHop{}, &awsconfig.AWSCredentials{}))
+}
@@ -0,0 +1,183 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/versent/saml2aws/pkg/awsconfig"
+	"github.com/versent/saml2aws/pkg/flags"
+	"github.com/versent/saml2aws/pkg/partition"
+)
+
+// Console open the AWS console signed in with the credentials of the profile, logging in first if they have expired
+func Console(consoleFlags *flags.ConsoleFlags) error {
+
+	logger := logrus.WithField("command", "console")
+
+	account, err := buildIdpAccount(consoleFlags.LoginExecFlags)
+	if err != nil {
+		return errors.Wrap(err, "error building login details")
+	}
+
+	sharedCreds := awsconfig.NewSharedCredentials(account.Profile)
+
+	if sharedCreds.Expired() {
+		logger.Debug("credentials expired, logging in")
+
+		err = loginIdpAccount(consoleFlags.LoginExecFlags)
+		if err != nil {
+			return errors.Wrap(err, "error logging in")
+		}
+	}
+
+	awsCreds, err := sharedCreds.Load()
+	if err != nil {
+		return errors.Wrap(err, "error loading credentials")
+	}
+
+	if time.Now().After(awsCreds.Expires) {
+		return errors.New("error aws credentials have expired")
+	}
+
+	awsPartition, err := partition.FromARN(awsCreds.PrincipalARN)
+	if err != nil {
+		awsPartition = partition.AWS
+	}
+
+	federationURL := consoleFlags.FederationURL
+	if federationURL == "" {
+		federationURL = awsPartition.FederationURL
+	}
+
+	signinToken, err := getSigninToken(federationURL, awsCreds)
+	if err != nil {
+		return err
+	}
+
+	loginURL, err := consoleLoginURL(federationURL, signinToken, consoleDestination(awsPartition, consoleFlags.Destination), consoleFlags.Issuer)
+	if err != nil {
+		return err
+	}
+
+	if consoleFlags.Link {
+		fmt.Println(loginURL)
+		return nil
+	}
+
+	fmt.Println("Opening the AWS console for", awsCreds.PrincipalARN)
+
+	return openBrowser(loginURL)
+}
+
+// getSigninToken exchange the credentials for a sign-in token at the federation endpoint
+func getSigninToken(federationURL string, awsCreds *awsconfig.AWSCredentials) (string, error) {
+	session, err := json.Marshal(map[string]string{
+		"sessionId":    awsCreds.AWSAccessKey,
+		"sessionKey":   awsCreds.AWSSecretKey,
+		"sessionToken": awsCreds.AWSSessionToken,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "error encoding console session")
+	}
+
+	tokenURL, err := url.Parse(federationURL)
+	if err != nil {
+		return "", errors.Wrap(err, "error parsing federation url")
+	}
+
+	tokenURL.RawQuery = url.Values{
+		"Action":  {"getSigninToken"},
+		"Session": {string(session)},
+	}.Encode()
+
+	res, err := http.Get(tokenURL.String())
+	if err != nil {
+		return "", errors.Wrap(err, "error retrieving sign-in token")
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "error reading sign-in token")
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return "", errors.Errorf("error retrieving sign-in token, federation endpoint returned %s", res.Status)
+	}
+
+	var token struct {
+		SigninToken string
+	}
+
+	err = json.Unmarshal(data, &token)
+	if err != nil {
+		return "", errors.Wrap(err, "error decoding sign-in token")
+	}
+
+	if token.SigninToken == "" {
+		return "", errors.New("federation endpoint didn't return a sign-in token")
+	}
+
+	return token.SigninToken, nil
+}
+
+// consoleLoginURL build the federation URL which signs in to the console with the token and redirects to the destination
+func consoleLoginURL(federationURL, signinToken, destination, issuer string) (string, error) {
+	loginURL, err := url.Parse(federationURL)
+	if err != nil {
+		return "", errors.Wrap(err, "error parsing federation url")
+	}
+
+	params := url.Values{
+		"Action":      {"login"},
+		"Destination": {destination},
+		"SigninToken": {signinToken},
+	}
+
+	if issuer != "" {
+		params.Set("Issuer", issuer)
+	}
+
+	loginURL.RawQuery = params.Encode()
+
+	return loginURL.String(), nil
+}
+
+// consoleDestination resolve a service path such as ec2/home against the console of the partition, full URLs are used
+// as they are
+func consoleDestination(p *partition.Partition, destination string) string {
+	if strings.HasPrefix(destination, "https://") || strings.HasPrefix(destination, "http://") {
+		return destination
+	}
+
+	return p.ConsoleURL + strings.TrimPrefix(destination, "/")
+}
+
+func openBrowser(target string) error {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+
+	err := cmd.Start()
+	if err != nil {
+		return errors.Wrap(err, "error opening browser, use --link to print the console url instead")
+	}
+
+	return nil
+}
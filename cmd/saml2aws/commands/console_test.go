@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/versent/saml2aws/pkg/awsconfig"
+	"github.com/versent/saml2aws/pkg/partition"
+)
+
+func TestGetSigninToken(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "getSigninToken", r.URL.Query().Get("Action"))
+
+		session := map[string]string{}
+		require.Nil(t, json.Unmarshal([]byte(r.URL.Query().Get("Session")), &session))
+		assert.Equal(t, map[string]string{"sessionId": "AKIA", "sessionKey": "secret", "sessionToken": "token"}, session)
+
+		_, _ = w.Write([]byte(`{"SigninToken":"signin-token"}`))
+	}))
+	defer ts.Close()
+
+	token, err := getSigninToken(ts.URL+"/federation", &awsconfig.AWSCredentials{
+		AWSAccessKey:    "AKIA",
+		AWSSecretKey:    "secret",
+		AWSSessionToken: "token",
+	})
+	require.Nil(t, err)
+	assert.Equal(t, "signin-token", token)
+}
+
+func TestGetSigninTokenError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	_, err := getSigninToken(ts.URL, &awsconfig.AWSCredentials{})
+	assert.Error(t, err)
+}
+
+func TestConsoleLoginURL(t *testing.T) {
+	loginURL, err := consoleLoginURL(partition.AWSUSGov.FederationURL, "signin-token", consoleDestination(partition.AWSUSGov, "ec2/home"), "saml2aws")
+	require.Nil(t, err)
+
+	u, err := url.Parse(loginURL)
+	require.Nil(t, err)
+	assert.Equal(t, "signin.amazonaws-us-gov.com", u.Host)
+	assert.Equal(t, "login", u.Query().Get("Action"))
+	assert.Equal(t, "signin-token", u.Query().Get("SigninToken"))
+	assert.Equal(t, "https://console.amazonaws-us-gov.com/ec2/home", u.Query().Get("Destination"))
+	assert.Equal(t, "saml2aws", u.Query().Get("Issuer"))
+}
+
+func TestConsoleDestination(t *testing.T) {
+	assert.Equal(t, "https://console.aws.amazon.com/", consoleDestination(partition.AWS, ""))
+	assert.Equal(t, "https://console.amazonaws.cn/s3/home", consoleDestination(partition.AWSCN, "/s3/home"))
+	assert.Equal(t, "https://example.com/", consoleDestination(partition.AWS, "https://example.com/"))
+}
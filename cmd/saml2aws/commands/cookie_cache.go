@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/versent/saml2aws"
+	"github.com/versent/saml2aws/helper/credentials"
+	"github.com/versent/saml2aws/pkg/cfg"
+	"github.com/versent/saml2aws/pkg/cookiecache"
+	"github.com/versent/saml2aws/pkg/cookiejar"
+	"github.com/versent/saml2aws/pkg/creds"
+	"github.com/versent/saml2aws/pkg/flags"
+	"github.com/versent/saml2aws/pkg/provider"
+)
+
+// resumeCachedSession share a jar holding the cookies cached for the idp account with its clients and ask the provider
+// for a SAML response from the IdP session in them, the response is empty when there is no session to resume or the
+// IdP rejected it, in which case the shared jar starts out empty for the full login
+func resumeCachedSession(out io.Writer, store cookiecache.Store, idpAccountName string, account *cfg.IDPAccount, loginDetails *creds.LoginDetails) (string, *cookiejar.Jar, error) {
+
+	logger := logrus.WithField("command", "authenticate")
+
+	jar, entry, err := restoreCookieJar(store, idpAccountName, account.URL, loginDetails.Username)
+	if err != nil {
+		return "", nil, err
+	}
+
+	provider.SetCookieJar(account, jar)
+
+	if entry == nil {
+		return "", jar, nil
+	}
+
+	samlAssertion, err := resumeSession(out, account, loginDetails, entry.Username)
+	if err == nil && samlAssertion != "" {
+		if loginDetails.Username == "" {
+			loginDetails.Username = entry.Username
+		}
+		return samlAssertion, jar, nil
+	}
+
+	logger.WithError(err).Debug("IdP rejected the cached session, authenticating without cookies")
+
+	jar, err = provider.NewCookieJar()
+	if err != nil {
+		return "", nil, err
+	}
+
+	provider.SetCookieJar(account, jar)
+
+	return "", jar, nil
+}
+
+// resumeSession ask the provider for a SAML response from the IdP session held in the shared cookies, providers which
+// can't tell a live session from a login page never resume one
+func resumeSession(out io.Writer, account *cfg.IDPAccount, loginDetails *creds.LoginDetails, username string) (string, error) {
+	client, err := saml2aws.NewSAMLClient(account)
+	if err != nil {
+		return "", errors.Wrap(err, "error building IdP client")
+	}
+
+	resumer, ok := client.(saml2aws.SessionResumer)
+	if !ok {
+		return "", nil
+	}
+
+	fmt.Fprintf(out, "Resuming IdP session of %s ...\n", username)
+
+	return resumer.ResumeSession(loginDetails)
+}
+
+func authenticateToIdP(account *cfg.IDPAccount, loginDetails *creds.LoginDetails) (string, error) {
+	client, err := saml2aws.NewSAMLClient(account)
+	if err != nil {
+		return "", errors.Wrap(err, "error building IdP client")
+	}
+
+	return client.Authenticate(loginDetails)
+}
+
+// restoreCookieJar build a jar holding the cookies cached for the idp account, the entry is nil when none were cached
+// for the IdP and user
+func restoreCookieJar(store cookiecache.Store, idpAccountName, url, username string) (*cookiejar.Jar, *cookiecache.Entry, error) {
+
+	logger := logrus.WithField("command", "authenticate")
+
+	jar, err := provider.NewCookieJar()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entry, err := store.Load(idpAccountName)
+	if err != nil {
+		if err != cookiecache.ErrNotFound {
+			logger.WithError(err).Debug("unable to load cached cookies")
+		}
+		return jar, nil, nil
+	}
+
+	if !entry.Valid(url, username) {
+		logger.WithField("username", entry.Username).Debug("cached cookies belong to another IdP session")
+		return jar, nil, nil
+	}
+
+	err = jar.Load(bytes.NewReader(entry.Cookies))
+	if err != nil {
+		logger.WithError(err).Debug("unable to decode cached cookies")
+		return jar, nil, nil
+	}
+
+	return jar, entry, nil
+}
+
+// cacheCookies save the cookies of the IdP session for the next login, a failure to cache only costs a full login
+func cacheCookies(store cookiecache.Store, idpAccountName string, account *cfg.IDPAccount, username string, jar *cookiejar.Jar) {
+	buf := new(bytes.Buffer)
+
+	err := jar.Save(buf)
+	if err == nil {
+		err = store.Save(idpAccountName, &cookiecache.Entry{URL: account.URL, Username: username, Cookies: buf.Bytes()})
+	}
+	if err != nil {
+		logrus.WithField("command", "authenticate").WithError(err).Debug("unable to cache cookies")
+	}
+}
+
+func cookieCacheStore(account *cfg.IDPAccount, loginFlags *flags.LoginExecFlags) (cookiecache.Store, error) {
+	if account.CookieCache == cookiecache.ModeKeychain && loginFlags.CommonFlags.DisableKeychain {
+		return nil, nil
+	}
+
+	return cookiecache.NewStore(account.CookieCache)
+}
+
+// clearCookieCache remove the cookies cached for the idp account from every store, the cache mode may have changed
+// since they were saved
+func clearCookieCache(idpAccountName string, disableKeychain bool) error {
+	fileStore, err := cookiecache.NewStore(cookiecache.ModeFile)
+	if err != nil {
+		return err
+	}
+
+	err = fileStore.Delete(idpAccountName)
+	if err != nil {
+		return err
+	}
+
+	if disableKeychain || !credentials.SupportsStorage() {
+		return nil
+	}
+
+	keychainStore, err := cookiecache.NewStore(cookiecache.ModeKeychain)
+	if err != nil {
+		return err
+	}
+
+	err = keychainStore.Delete(idpAccountName)
+	if err != nil {
+		// the keychain may never have held any cookies
+		logrus.WithField("command", "logout").WithError(err).Debug("unable to remove cached cookies from keychain")
+	}
+
+	return nil
+}
@@ -0,0 +1,258 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/versent/saml2aws/helper/credentials"
+	"github.com/versent/saml2aws/pkg/awsconfig"
+	"github.com/versent/saml2aws/pkg/cfg"
+	"github.com/versent/saml2aws/pkg/flags"
+	"github.com/versent/saml2aws/pkg/flock"
+)
+
+const (
+	// credentialProcessURLPrefix prefix of the keychain entries holding the credentials issued to credential-process
+	credentialProcessURLPrefix = "saml2aws-credential-process://"
+
+	// credentialProcessExpiryMargin credentials closer than this to expiring are refreshed rather than reused
+	credentialProcessExpiryMargin = 5 * time.Minute
+)
+
+// CredentialProcess print the credentials of the role as the JSON expected from an AWS credential_process, logging in
+// only when the credentials issued last time have expired
+func CredentialProcess(loginFlags *flags.LoginExecFlags) error {
+
+	account, err := buildIdpAccount(loginFlags)
+	if err != nil {
+		return errors.Wrap(err, "error building login details")
+	}
+
+	// stdout only carries the credentials, anything printed while logging in goes to stderr
+	awsCreds, err := loadRoleCredentials(os.Stderr, account, loginFlags)
+	if err != nil {
+		return err
+	}
+
+	data, err := awsCreds.CredentialProcessJSON()
+	if err != nil {
+		return errors.Wrap(err, "error encoding credentials")
+	}
+
+	_, err = fmt.Fprintln(os.Stdout, string(data))
+
+	return err
+}
+
+// loadRoleCredentials return the credentials of the role issued last time if they are still valid, otherwise log in
+// and cache the new ones
+func loadRoleCredentials(out io.Writer, account *cfg.IDPAccount, loginFlags *flags.LoginExecFlags) (*awsconfig.AWSCredentials, error) {
+
+	logger := logrus.WithField("command", "credential-process")
+
+	idpAccountName := loginFlags.CommonFlags.IdpAccount
+	cache := credentialProcessCacheEnabled(loginFlags)
+
+	// a pattern only resolves to a role once the assertion is in hand, an exact role ARN can be looked up right away
+	if cache && isRoleARN(account.RoleARN) {
+		awsCreds := loadCachedRoleCredentials(idpAccountName, account.RoleARN, time.Now())
+		if awsCreds != nil {
+			return awsCreds, nil
+		}
+	}
+
+	samlAssertion, err := authenticateWithCache(out, account, loginFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	role, assertion, err := selectAwsRole(out, samlAssertion, account)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to assume role, please check whether you are permitted to assume the given role for the AWS service")
+	}
+
+	if cache && role.RoleARN != account.RoleARN {
+		awsCreds := loadCachedRoleCredentials(idpAccountName, role.RoleARN, time.Now())
+		if awsCreds != nil {
+			return awsCreds, nil
+		}
+	}
+
+	awsCreds, err := loginToStsUsingRole(out, account, role, samlAssertion, assertion.SessionDuration)
+	if err != nil {
+		return nil, errors.Wrap(err, "error logging into aws role using saml assertion")
+	}
+
+	awsCreds, err = assumeChain(out, account, awsCreds, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "error assuming roles of assume_chain")
+	}
+
+	if cache {
+		err = saveCachedRoleCredentials(idpAccountName, role.RoleARN, awsCreds)
+		if err != nil {
+			// a failure to cache only costs another login next time
+			logger.WithError(err).Debug("unable to cache credentials")
+		}
+	}
+
+	return awsCreds, nil
+}
+
+// credentialProcessCacheEnabled credentials are only cached in the keychain, independently of the assertion cache
+func credentialProcessCacheEnabled(loginFlags *flags.LoginExecFlags) bool {
+	if loginFlags.CommonFlags.DisableCredentialCache {
+		return false
+	}
+
+	return !loginFlags.CommonFlags.DisableKeychain && credentials.SupportsStorage()
+}
+
+// isRoleARN returns true if the role of the idp account is an exact role ARN rather than a pattern
+func isRoleARN(role string) bool {
+	return strings.HasPrefix(role, "arn:") && !strings.ContainsAny(role, "*?")
+}
+
+func credentialProcessURL(idpAccountName, roleARN string) string {
+	return credentialProcessURLPrefix + idpAccountName + "/" + roleARN
+}
+
+// credentialProcessIndexURL the keychain entry listing the roles of the idp account with cached credentials, the
+// keychain can't be searched so logout reads it to find them
+func credentialProcessIndexURL(idpAccountName string) string {
+	return credentialProcessURLPrefix + idpAccountName
+}
+
+// loadCachedRoleCredentials load the credentials cached for the idp account and role, returning nil if there are none
+// or they are about to expire
+func loadCachedRoleCredentials(idpAccountName, roleARN string, now time.Time) *awsconfig.AWSCredentials {
+
+	logger := logrus.WithField("command", "credential-process")
+
+	_, secret, err := credentials.CurrentHelper.Get(credentialProcessURL(idpAccountName, roleARN))
+	if err != nil {
+		if !credentials.IsErrCredentialsNotFound(err) {
+			logger.WithError(err).Debug("unable to load cached credentials")
+		}
+		return nil
+	}
+
+	awsCreds := new(awsconfig.AWSCredentials)
+
+	err = json.Unmarshal([]byte(secret), awsCreds)
+	if err != nil {
+		logger.WithError(err).Debug("unable to decode cached credentials")
+		return nil
+	}
+
+	if awsCreds.Expires.Before(now.Add(credentialProcessExpiryMargin)) {
+		return nil
+	}
+
+	return awsCreds
+}
+
+func saveCachedRoleCredentials(idpAccountName, roleARN string, awsCreds *awsconfig.AWSCredentials) error {
+	data, err := json.Marshal(awsCreds)
+	if err != nil {
+		return err
+	}
+
+	err = credentials.CurrentHelper.Add(&credentials.Credentials{
+		ServerURL: credentialProcessURL(idpAccountName, roleARN),
+		Username:  idpAccountName,
+		Secret:    string(data),
+	})
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockCredentialProcessIndex(idpAccountName)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	roleARNs := loadCachedRoleARNs(idpAccountName)
+	for _, cached := range roleARNs {
+		if cached == roleARN {
+			return nil
+		}
+	}
+
+	data, err = json.Marshal(append(roleARNs, roleARN))
+	if err != nil {
+		return err
+	}
+
+	return credentials.CurrentHelper.Add(&credentials.Credentials{
+		ServerURL: credentialProcessIndexURL(idpAccountName),
+		Username:  idpAccountName,
+		Secret:    string(data),
+	})
+}
+
+// loadCachedRoleARNs the roles of the idp account with cached credentials
+func loadCachedRoleARNs(idpAccountName string) []string {
+	_, secret, err := credentials.CurrentHelper.Get(credentialProcessIndexURL(idpAccountName))
+	if err != nil {
+		return nil
+	}
+
+	var roleARNs []string
+
+	err = json.Unmarshal([]byte(secret), &roleARNs)
+	if err != nil {
+		logrus.WithField("command", "credential-process").WithError(err).Debug("unable to decode cached roles")
+		return nil
+	}
+
+	return roleARNs
+}
+
+// clearCredentialProcessCache remove the credentials cached for every role of the idp account
+func clearCredentialProcessCache(idpAccountName string) error {
+
+	logger := logrus.WithField("command", "logout")
+
+	lock, err := lockCredentialProcessIndex(idpAccountName)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	roleARNs := loadCachedRoleARNs(idpAccountName)
+	if roleARNs == nil {
+		return nil
+	}
+
+	for _, roleARN := range roleARNs {
+		err = credentials.CurrentHelper.Delete(credentialProcessURL(idpAccountName, roleARN))
+		if err != nil {
+			// the credentials may have been removed from the keychain by hand
+			logger.WithError(err).WithField("role", roleARN).Debug("unable to remove cached credentials")
+		}
+	}
+
+	return credentials.CurrentHelper.Delete(credentialProcessIndexURL(idpAccountName))
+}
+
+// lockCredentialProcessIndex parallel credential-process runs for different roles of the idp account each add their
+// role to the index
+func lockCredentialProcessIndex(idpAccountName string) (*flock.Lock, error) {
+	dir, err := homedir.Expand(DefaultLoginLockDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return flock.Acquire(filepath.Join(dir, fmt.Sprintf("%s-credential-process.lock", url.PathEscape(idpAccountName))))
+}
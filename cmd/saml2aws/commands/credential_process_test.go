@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/versent/saml2aws/helper/credentials"
+	"github.com/versent/saml2aws/pkg/awsconfig"
+	"github.com/versent/saml2aws/pkg/flags"
+	"github.com/versent/saml2aws/pkg/samlcache"
+)
+
+type memoryHelper struct {
+	secrets map[string]string
+}
+
+func (m *memoryHelper) Add(creds *credentials.Credentials) error {
+	m.secrets[creds.ServerURL] = creds.Secret
+	return nil
+}
+
+func (m *memoryHelper) Delete(serverURL string) error {
+	delete(m.secrets, serverURL)
+	return nil
+}
+
+func (m *memoryHelper) Get(serverURL string) (string, string, error) {
+	secret, ok := m.secrets[serverURL]
+	if !ok {
+		return "", "", credentials.ErrCredentialsNotFound
+	}
+	return "", secret, nil
+}
+
+func (m *memoryHelper) SupportsCredentialStorage() bool {
+	return true
+}
+
+func TestCredentialProcessCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credential-process")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	previousHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", previousHome)
+	os.Setenv("HOME", dir)
+
+	homedir.DisableCache = true
+	defer func() { homedir.DisableCache = false }()
+
+	previous := credentials.CurrentHelper
+	defer func() { credentials.CurrentHelper = previous }()
+
+	helper := &memoryHelper{secrets: map[string]string{}}
+	credentials.CurrentHelper = helper
+
+	roleARN := "arn:aws:iam::000000000001:role/Development"
+	now := time.Now()
+
+	assert.Nil(t, loadCachedRoleCredentials("default", roleARN, now))
+
+	err = saveCachedRoleCredentials("default", roleARN, &awsconfig.AWSCredentials{
+		AWSAccessKey: "AKIA",
+		Expires:      now.Add(time.Hour),
+	})
+	require.Nil(t, err)
+
+	assert.Contains(t, helper.secrets, "saml2aws-credential-process://default/arn:aws:iam::000000000001:role/Development")
+
+	awsCreds := loadCachedRoleCredentials("default", roleARN, now)
+	require.NotNil(t, awsCreds)
+	assert.Equal(t, "AKIA", awsCreds.AWSAccessKey)
+
+	// each role and idp account has its own credentials
+	assert.Nil(t, loadCachedRoleCredentials("default", "arn:aws:iam::000000000001:role/ReadOnly", now))
+	assert.Nil(t, loadCachedRoleCredentials("other", roleARN, now))
+
+	// credentials about to expire are refreshed
+	assert.Nil(t, loadCachedRoleCredentials("default", roleARN, now.Add(time.Hour-time.Minute)))
+
+	err = saveCachedRoleCredentials("default", "arn:aws:iam::000000000001:role/ReadOnly", &awsconfig.AWSCredentials{AWSAccessKey: "AKIA", Expires: now.Add(time.Hour)})
+	require.Nil(t, err)
+	err = saveCachedRoleCredentials("default", roleARN, &awsconfig.AWSCredentials{AWSAccessKey: "AKIA", Expires: now.Add(time.Hour)})
+	require.Nil(t, err)
+	err = saveCachedRoleCredentials("other", roleARN, &awsconfig.AWSCredentials{AWSAccessKey: "AKIA", Expires: now.Add(time.Hour)})
+	require.Nil(t, err)
+
+	assert.Equal(t, []string{roleARN, "arn:aws:iam::000000000001:role/ReadOnly"}, loadCachedRoleARNs("default"))
+
+	// logout removes the credentials of every role of the idp account and nothing else
+	require.Nil(t, clearCredentialProcessCache("default"))
+	assert.Equal(t, map[string]string{
+		"saml2aws-credential-process://other/arn:aws:iam::000000000001:role/Development": helper.secrets["saml2aws-credential-process://other/arn:aws:iam::000000000001:role/Development"],
+		"saml2aws-credential-process://other":                                            `["arn:aws:iam::000000000001:role/Development"]`,
+	}, helper.secrets)
+}
+
+func TestIsRoleARN(t *testing.T) {
+	assert.True(t, isRoleARN("arn:aws:iam::000000000001:role/Development"))
+	assert.False(t, isRoleARN("arn:aws:iam::*:role/Development"))
+	assert.False(t, isRoleARN("production/Development"))
+	assert.False(t, isRoleARN(""))
+}
+
+func TestCredentialProcessStdout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credential-process")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	previousHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", previousHome)
+	os.Setenv("HOME", dir)
+
+	homedir.DisableCache = true
+	defer func() { homedir.DisableCache = false }()
+
+	err = ioutil.WriteFile(filepath.Join(dir, ".saml2aws"), []byte(`[default]
+url             = https://id.example.com
+username        = wolfeidau
+provider        = KeyCloak
+mfa             = Auto
+assertion_cache = keychain
+role_arn        = arn:aws:iam::123123123123:role/AWS-Admin-CloudOPSNonProd*
+`), 0600)
+	require.Nil(t, err)
+
+	previousHelper := credentials.CurrentHelper
+	defer func() { credentials.CurrentHelper = previousHelper }()
+	credentials.CurrentHelper = &memoryHelper{secrets: map[string]string{}}
+
+	// the cached assertion names a role session, which is printed while the role is resolved
+	data, err := ioutil.ReadFile("../../../testdata/assertion_session_tags.xml")
+	require.Nil(t, err)
+
+	err = (&samlcache.KeychainStore{}).Save("default", &samlcache.Entry{
+		URL:          "https://id.example.com",
+		Username:     "wolfeidau",
+		SAMLResponse: base64.StdEncoding.EncodeToString(data),
+		NotOnOrAfter: time.Now().Add(time.Hour),
+	})
+	require.Nil(t, err)
+
+	err = saveCachedRoleCredentials("default", "arn:aws:iam::123123123123:role/AWS-Admin-CloudOPSNonProd", &awsconfig.AWSCredentials{
+		AWSAccessKey:    "AKIA",
+		AWSSecretKey:    "secret",
+		AWSSessionToken: "token",
+		Expires:         time.Now().Add(time.Hour),
+	})
+	require.Nil(t, err)
+
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	require.Nil(t, err)
+	stderrReader, stderrWriter, err := os.Pipe()
+	require.Nil(t, err)
+
+	previousStdout, previousStderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = stdoutWriter, stderrWriter
+
+	err = CredentialProcess(&flags.LoginExecFlags{CommonFlags: &flags.CommonFlags{IdpAccount: "default", SkipPrompt: true}})
+
+	os.Stdout, os.Stderr = previousStdout, previousStderr
+	stdoutWriter.Close()
+	stderrWriter.Close()
+	require.Nil(t, err)
+
+	stdout, err := ioutil.ReadAll(stdoutReader)
+	require.Nil(t, err)
+	stderr, err := ioutil.ReadAll(stderrReader)
+	require.Nil(t, err)
+
+	output := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(stdout))
+	require.Nil(t, decoder.Decode(&output))
+	assert.False(t, decoder.More())
+	assert.Equal(t, "AKIA", output["AccessKeyId"])
+
+	assert.Contains(t, string(stderr), "Role session name:")
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/versent/saml2aws/pkg/awsconfig"
 	"github.com/versent/saml2aws/pkg/flags"
+	"github.com/versent/saml2aws/pkg/partition"
 	"github.com/versent/saml2aws/pkg/shell"
 )
 
@@ -28,6 +29,10 @@ func Exec(execFlags *flags.LoginExecFlags, cmdline []string) error {
 		return errors.Wrap(err, "error building login details")
 	}
 
+	if execFlags.WithServer {
+		return execWithServer(account, execFlags, cmdline)
+	}
+
 	sharedCreds := awsconfig.NewSharedCredentials(account.Profile)
 
 	// this checks if the credentials file has been created yet
@@ -47,32 +52,51 @@ func Exec(execFlags *flags.LoginExecFlags, cmdline []string) error {
 		return errors.Wrap(err, "error loading credentials")
 	}
 
-	if awsCreds.Expires.Sub(time.Now()) < 0 {
-		return errors.New("error aws credentials have expired")
-	}
-
-	ok, err := checkToken(account.Profile)
-	if err != nil {
-		return errors.Wrap(err, "error validating token")
+	ok := time.Now().Before(awsCreds.Expires)
+	if ok {
+		ok, err = checkToken(account.Profile, awsCreds.PrincipalARN)
+		if err != nil {
+			return errors.Wrap(err, "error validating token")
+		}
 	}
 
 	if !ok {
-		err = Login(execFlags)
-	}
-	if err != nil {
-		return errors.Wrap(err, "error logging in")
+		// parallel exec calls share the one login, so the credentials are reloaded from whichever process logged in
+		err = loginIdpAccount(execFlags)
+		if err != nil {
+			return errors.Wrap(err, "error logging in")
+		}
+
+		awsCreds, err = sharedCreds.Load()
+		if err != nil {
+			return errors.Wrap(err, "error loading credentials")
+		}
 	}
 
 	if execFlags.ExecProfile != "" {
 		// Assume the desired role before generating env vars
+		samlCreds := awsCreds
 		awsCreds, err = assumeRoleWithProfile(execFlags.ExecProfile, execFlags.CommonFlags.SessionDuration)
 		if err != nil {
 			return errors.Wrap(err,
 				fmt.Sprintf("error acquiring credentials for profile: %s", execFlags.ExecProfile))
 		}
+
+		// the chained session still originates from the SAML login
+		awsCreds.RoleSessionName = samlCreds.RoleSessionName
+		awsCreds.SourceIdentity = samlCreds.SourceIdentity
 	}
 
-	return shell.ExecShellCmd(cmdline, shell.BuildEnvVars(awsCreds, account, execFlags))
+	return runCmd(execFlags, cmdline, shell.BuildEnvVars(awsCreds, account, execFlags))
+}
+
+// runCmd run the command directly when asked to, otherwise through the shell
+func runCmd(execFlags *flags.LoginExecFlags, cmdline []string, envVars []string) error {
+	if execFlags.Direct {
+		return shell.ExecCmd(cmdline, envVars)
+	}
+
+	return shell.ExecShellCmd(cmdline, envVars)
 }
 
 // assumeRoleWithProfile uses an AWS profile (via ~/.aws/config) and performs (multiple levels of) role assumption
@@ -110,10 +134,17 @@ func assumeRoleWithProfile(targetProfile string, sessionDuration int) (*awsconfi
 	}, nil
 }
 
-func checkToken(profile string) (bool, error) {
-	sess, err := session.NewSessionWithOptions(session.Options{
+func checkToken(profile, principalARN string) (bool, error) {
+	options := session.Options{
 		Profile: profile,
-	})
+	}
+
+	// without a region STS can't be called, so fall back to one in the partition the credentials belong to
+	if awsPartition, err := partition.FromARN(principalARN); err == nil {
+		options.Config = *aws.NewConfig().WithRegion(awsPartition.ResolveRegion())
+	}
+
+	sess, err := session.NewSessionWithOptions(options)
 	if err != nil {
 		return false, err
 	}
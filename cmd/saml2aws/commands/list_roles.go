@@ -1,14 +1,13 @@
 package commands
 
 import (
-	"encoding/base64"
 	"fmt"
 	"os"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/versent/saml2aws"
-	"github.com/versent/saml2aws/helper/credentials"
+	"github.com/versent/saml2aws/pkg/cfg"
 	"github.com/versent/saml2aws/pkg/flags"
 )
 
@@ -22,82 +21,47 @@ func ListRoles(loginFlags *flags.LoginExecFlags) error {
 		return errors.Wrap(err, "error building login details")
 	}
 
-	loginDetails, err := resolveLoginDetails(account, loginFlags)
-	if err != nil {
-		fmt.Printf("%+v\n", err)
-		os.Exit(1)
-	}
+	logger.WithField("idpAccount", account).Debug("listing roles")
 
-	err = loginDetails.Validate()
+	samlAssertion, err := authenticateWithCache(os.Stdout, account, loginFlags)
 	if err != nil {
-		return errors.Wrap(err, "error validating login details")
-	}
-
-	logger.WithField("idpAccount", account).Debug("building provider")
-
-	provider, err := saml2aws.NewSAMLClient(account)
-	if err != nil {
-		return errors.Wrap(err, "error building IdP client")
-	}
-
-	samlAssertion, err := provider.Authenticate(loginDetails)
-	if err != nil {
-		return errors.Wrap(err, "error authenticating to IdP")
-
+		return err
 	}
 
-	if samlAssertion == "" {
-		fmt.Println("Response did not contain a valid SAML assertion")
-		fmt.Println("Please check your username and password is correct")
-		os.Exit(1)
-	}
-
-	if !loginFlags.CommonFlags.DisableKeychain {
-		err = credentials.SaveCredentials(loginDetails.URL, loginDetails.Username, loginDetails.Password)
-		if err != nil {
-			return errors.Wrap(err, "error storing password in keychain")
-		}
-	}
-
-	data, err := base64.StdEncoding.DecodeString(samlAssertion)
+	data, err := decodeSAMLAssertion(samlAssertion, account)
 	if err != nil {
-		return errors.Wrap(err, "error decoding saml assertion")
+		return err
 	}
 
-	roles, err := saml2aws.ExtractAwsRoles(data)
+	assertion, err := saml2aws.ParseSAMLAssertion(data)
 	if err != nil {
-		return errors.Wrap(err, "error parsing aws roles")
+		return errors.Wrap(err, "error parsing saml assertion")
 	}
 
-	if len(roles) == 0 {
+	if len(assertion.Roles) == 0 {
 		fmt.Println("No roles to assume")
 		os.Exit(1)
 	}
 
-	awsRoles, err := saml2aws.ParseAWSRoles(roles)
-	if err != nil {
-		return errors.Wrap(err, "error parsing aws roles")
-	}
-
-	if err := listRoles(awsRoles, samlAssertion, loginFlags); err != nil {
+	if err := listRoles(assertion.Roles, samlAssertion, account); err != nil {
 		return errors.Wrap(err, "Failed to list roles")
 	}
 
+	printSessionAttributes(os.Stdout, assertion)
+
 	return nil
 }
 
-func listRoles(awsRoles []*saml2aws.AWSRole, samlAssertion string, loginFlags *flags.LoginExecFlags) error {
-	awsAccounts, err := saml2aws.ParseAWSAccounts(samlAssertion)
+func listRoles(awsRoles []*saml2aws.AWSRole, samlAssertion string, account *cfg.IDPAccount) error {
+	awsAccounts, err := resolveAWSAccounts(awsRoles, samlAssertion, account)
 	if err != nil {
-		errors.Wrap(err, "error parsing aws role accounts")
+		return err
 	}
 
-	saml2aws.AssignPrincipals(awsRoles, awsAccounts)
-
 	fmt.Println("")
-	for _, account := range awsAccounts {
-		fmt.Println(account.Name)
-		for _, role := range account.Roles {
+	for _, awsAccount := range awsAccounts {
+		fmt.Println(awsAccount.Name)
+		for _, role := range awsAccount.Roles {
 			fmt.Println(role.RoleARN)
 		}
 		fmt.Println("")
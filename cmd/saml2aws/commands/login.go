@@ -1,8 +1,10 @@
 package commands
 
 import (
+	"crypto/rsa"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -14,13 +16,34 @@ import (
 	"github.com/versent/saml2aws/helper/credentials"
 	"github.com/versent/saml2aws/pkg/awsconfig"
 	"github.com/versent/saml2aws/pkg/cfg"
+	"github.com/versent/saml2aws/pkg/cookiejar"
 	"github.com/versent/saml2aws/pkg/creds"
 	"github.com/versent/saml2aws/pkg/flags"
+	"github.com/versent/saml2aws/pkg/partition"
+	"github.com/versent/saml2aws/pkg/prompter"
+	"github.com/versent/saml2aws/pkg/provider"
+	"github.com/versent/saml2aws/pkg/samlcache"
 )
 
-// Login login to ADFS
+// Login login to ADFS, logging in to each account concurrently when several idp accounts are given
 func Login(loginFlags *flags.LoginExecFlags) error {
 
+	names, err := resolveIdpAccountNames(loginFlags)
+	if err != nil {
+		return err
+	}
+
+	if len(names) == 1 && !loginFlags.All {
+		loginFlags.CommonFlags.IdpAccount = names[0]
+		return loginIdpAccount(loginFlags)
+	}
+
+	return loginIdpAccounts(loginFlags, names)
+}
+
+// loginIdpAccount login to the idp account of the flags
+func loginIdpAccount(loginFlags *flags.LoginExecFlags) error {
+
 	logger := logrus.WithField("command", "login")
 
 	account, err := buildIdpAccount(loginFlags)
@@ -42,63 +65,241 @@ func Login(loginFlags *flags.LoginExecFlags) error {
 		return nil
 	}
 
+	selections, err := buildRoleSelections(loginFlags, account)
+	if err != nil {
+		return errors.Wrap(err, "error building role selections")
+	}
+
+	if len(selections) > 0 {
+		if !roleProfilesExpired(selections) && !loginFlags.Force {
+			fmt.Println("credentials are not expired skipping")
+			return nil
+		}
+
+		lock, waited, err := lockLogin(loginFlags.CommonFlags.IdpAccount, account.Profile)
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+
+		if waited && !roleProfilesExpired(selections) {
+			fmt.Fprintln(os.Stderr, "credentials were refreshed by another saml2aws process")
+			return nil
+		}
+
+		samlAssertion, err := authenticateWithCache(os.Stdout, account, loginFlags)
+		if err != nil {
+			return err
+		}
+
+		return loginToRoles(loginFlags.CommonFlags.IdpAccount, account, samlAssertion, selections)
+	}
+
 	if !sharedCreds.Expired() && !loginFlags.Force {
 		fmt.Println("credentials are not expired skipping")
 		return nil
 	}
 
-	loginDetails, err := resolveLoginDetails(account, loginFlags)
+	lock, waited, err := lockLogin(loginFlags.CommonFlags.IdpAccount, account.Profile)
 	if err != nil {
-		fmt.Printf("%+v\n", err)
-		os.Exit(1)
+		return err
 	}
+	defer lock.Release()
 
-	err = loginDetails.Validate()
+	if waited && !sharedCreds.Expired() {
+		fmt.Fprintln(os.Stderr, "credentials were refreshed by another saml2aws process")
+		return nil
+	}
+
+	samlAssertion, err := authenticateWithCache(os.Stdout, account, loginFlags)
 	if err != nil {
-		return errors.Wrap(err, "error validating login details")
+		return err
 	}
 
-	logger.WithField("idpAccount", account).Debug("building provider")
+	endPrompts := beginLoginPrompts(loginFlags.CommonFlags.IdpAccount)
+	role, assertion, err := selectAwsRole(os.Stdout, samlAssertion, account)
+	endPrompts()
+	if err != nil {
+		return errors.Wrap(err, "Failed to assume role, please check whether you are permitted to assume the given role for the AWS service")
+	}
+
+	fmt.Println("Selected role:", role.RoleARN)
 
-	provider, err := saml2aws.NewSAMLClient(account)
+	awsCreds, err := loginToStsUsingRole(os.Stdout, account, role, samlAssertion, assertion.SessionDuration)
 	if err != nil {
-		return errors.Wrap(err, "error building IdP client")
+		return errors.Wrap(err, "error logging into aws role using saml assertion")
 	}
 
-	fmt.Printf("Authenticating as %s ...\n", loginDetails.Username)
+	awsCreds.RoleSessionName = assertion.RoleSessionName
+	awsCreds.SourceIdentity = assertion.SourceIdentity
+	awsCreds.IdpAccount = loginFlags.CommonFlags.IdpAccount
 
-	samlAssertion, err := provider.Authenticate(loginDetails)
+	awsCreds, err = assumeChain(os.Stdout, account, awsCreds, true)
 	if err != nil {
-		return errors.Wrap(err, "error authenticating to IdP")
+		return errors.Wrap(err, "error assuming roles of assume_chain")
+	}
+
+	return saveCredentials(account, awsCreds, sharedCreds)
+}
+
+// beginLoginPrompts hold the prompter while the login details or the role of the idp account are asked for, so the
+// questions of concurrent logins are asked back to back under the name of the idp account they belong to
+func beginLoginPrompts(idpAccountName string) func() {
+	endPrompts := prompter.BeginSession()
+
+	if concurrentLogins {
+		fmt.Printf("\n== %s ==\n", idpAccountName)
+	}
+
+	return endPrompts
+}
+
+// authenticate resolve the login details and authenticate to the IdP returning the base64 encoded SAML response, a live
+// IdP session in the cached cookies is resumed without prompting for the password
+func authenticate(out io.Writer, account *cfg.IDPAccount, loginFlags *flags.LoginExecFlags) (string, error) {
+
+	logger := logrus.WithField("command", "authenticate")
+
+	idpAccountName := loginFlags.CommonFlags.IdpAccount
+
+	loginDetails, err := resolveLoginDetails(out, account, loginFlags)
+	if err != nil {
+		return "", err
+	}
+
+	store, err := cookieCacheStore(account, loginFlags)
+	if err != nil {
+		return "", errors.Wrap(err, "error building cookie cache")
+	}
+
+	var jar *cookiejar.Jar
+	if store != nil {
+		var samlAssertion string
+
+		samlAssertion, jar, err = resumeCachedSession(out, store, idpAccountName, account, loginDetails)
+		if err != nil {
+			return "", err
+		}
+		defer provider.SetCookieJar(account, nil)
+
+		if samlAssertion != "" {
+			cacheCookies(store, idpAccountName, account, loginDetails.Username, jar)
+			return samlAssertion, nil
+		}
+	}
+
+	err = promptForLoginDetails(account, loginDetails, loginFlags)
+	if err != nil {
+		return "", err
+	}
+
+	err = loginDetails.Validate()
+	if err != nil {
+		return "", errors.Wrap(err, "error validating login details")
+	}
+
+	logger.WithField("idpAccount", account).Debug("building provider")
+
+	fmt.Fprintf(out, "Authenticating as %s ...\n", loginDetails.Username)
+
+	samlAssertion, err := authenticateToIdP(account, loginDetails)
+	if err != nil {
+		return "", errors.Wrap(err, "error authenticating to IdP")
 
 	}
 
 	if samlAssertion == "" {
-		fmt.Println("Response did not contain a valid SAML assertion")
-		fmt.Println("Please check your username and password is correct")
-		os.Exit(1)
+		return "", errors.New("response did not contain a valid SAML assertion, please check your username and password is correct")
+	}
+
+	if store != nil {
+		cacheCookies(store, idpAccountName, account, loginDetails.Username, jar)
 	}
 
 	if !loginFlags.CommonFlags.DisableKeychain {
 		err = credentials.SaveCredentials(loginDetails.URL, loginDetails.Username, loginDetails.Password)
 		if err != nil {
-			return errors.Wrap(err, "error storing password in keychain")
+			return "", errors.Wrap(err, "error storing password in keychain")
 		}
 	}
 
-	role, err := selectAwsRole(samlAssertion, account)
+	return samlAssertion, nil
+}
+
+// authenticateWithCache reuse the cached SAML response for the idp account while it is still valid, otherwise
+// authenticate to the IdP and cache the new response
+func authenticateWithCache(out io.Writer, account *cfg.IDPAccount, loginFlags *flags.LoginExecFlags) (string, error) {
+
+	logger := logrus.WithField("command", "authenticate")
+
+	idpAccountName := loginFlags.CommonFlags.IdpAccount
+
+	store, err := assertionCacheStore(account, loginFlags)
 	if err != nil {
-		return errors.Wrap(err, "Failed to assume role, please check whether you are permitted to assume the given role for the AWS service")
+		return "", errors.Wrap(err, "error building assertion cache")
 	}
 
-	fmt.Println("Selected role:", role.RoleARN)
+	if store != nil {
+		entry, err := store.Load(idpAccountName)
+		if err == nil && entry.Valid(account.URL, account.Username) {
+			fmt.Fprintf(out, "Using cached SAML assertion for %s valid until %v\n", idpAccountName, entry.NotOnOrAfter.Local())
+			return entry.SAMLResponse, nil
+		}
+		if err != nil && err != samlcache.ErrNotFound {
+			logger.WithError(err).Debug("unable to load cached saml assertion")
+		}
+	}
 
-	awsCreds, err := loginToStsUsingRole(account, role, samlAssertion)
+	samlAssertion, err := authenticate(out, account, loginFlags)
 	if err != nil {
-		return errors.Wrap(err, "error logging into aws role using saml assertion")
+		return "", err
 	}
 
-	return saveCredentials(awsCreds, sharedCreds)
+	if store != nil {
+		err = cacheSAMLAssertion(store, idpAccountName, account, samlAssertion)
+		if err != nil {
+			// a failure to cache only costs another login later
+			logger.WithError(err).Debug("unable to cache saml assertion")
+		}
+	}
+
+	return samlAssertion, nil
+}
+
+func assertionCacheStore(account *cfg.IDPAccount, loginFlags *flags.LoginExecFlags) (samlcache.Store, error) {
+	if loginFlags.CommonFlags.DisableAssertionCache {
+		return nil, nil
+	}
+
+	if account.AssertionCache == samlcache.ModeKeychain && loginFlags.CommonFlags.DisableKeychain {
+		return nil, nil
+	}
+
+	return samlcache.NewStore(account.AssertionCache)
+}
+
+func cacheSAMLAssertion(store samlcache.Store, idpAccountName string, account *cfg.IDPAccount, samlAssertion string) error {
+	data, err := decodeSAMLAssertion(samlAssertion, account)
+	if err != nil {
+		return err
+	}
+
+	assertion, err := saml2aws.ParseSAMLAssertion(data)
+	if err != nil {
+		return err
+	}
+
+	expires := assertion.Expires()
+	if expires.IsZero() {
+		return errors.New("saml assertion has no expiry")
+	}
+
+	return store.Save(idpAccountName, &samlcache.Entry{
+		URL:          account.URL,
+		Username:     account.Username,
+		SAMLResponse: samlAssertion,
+		NotOnOrAfter: expires,
+	})
 }
 
 func buildIdpAccount(loginFlags *flags.LoginExecFlags) (*cfg.IDPAccount, error) {
@@ -115,6 +316,11 @@ func buildIdpAccount(loginFlags *flags.LoginExecFlags) (*cfg.IDPAccount, error)
 	// update username and hostname if supplied
 	flags.ApplyFlagOverrides(loginFlags.CommonFlags, account)
 
+	err = applyRoleAlias(account, loginFlags.CommonFlags)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to apply role alias")
+	}
+
 	err = account.Validate()
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to validate account")
@@ -123,13 +329,13 @@ func buildIdpAccount(loginFlags *flags.LoginExecFlags) (*cfg.IDPAccount, error)
 	return account, nil
 }
 
-func resolveLoginDetails(account *cfg.IDPAccount, loginFlags *flags.LoginExecFlags) (*creds.LoginDetails, error) {
+func resolveLoginDetails(out io.Writer, account *cfg.IDPAccount, loginFlags *flags.LoginExecFlags) (*creds.LoginDetails, error) {
 
 	// fmt.Printf("loginFlags %+v\n", loginFlags)
 
 	loginDetails := &creds.LoginDetails{URL: account.URL, Username: account.Username, MFAToken: loginFlags.CommonFlags.MFAToken, DuoMFAOption: loginFlags.DuoMFAOption}
 
-	fmt.Printf("Using IDP Account %s to access %s %s\n", loginFlags.CommonFlags.IdpAccount, account.Provider, account.URL)
+	fmt.Fprintf(out, "Using IDP Account %s to access %s %s\n", loginFlags.CommonFlags.IdpAccount, account.Provider, account.URL)
 
 	var err error
 	if !loginFlags.CommonFlags.DisableKeychain {
@@ -155,104 +361,199 @@ func resolveLoginDetails(account *cfg.IDPAccount, loginFlags *flags.LoginExecFla
 
 	// fmt.Printf("loginDetails %+v\n", loginDetails)
 
-	// if skip prompt was passed just pass back the flag values
+	return loginDetails, nil
+}
+
+// promptForLoginDetails prompt for the login details the keychain and flags left out, unless skip prompt was passed
+func promptForLoginDetails(account *cfg.IDPAccount, loginDetails *creds.LoginDetails, loginFlags *flags.LoginExecFlags) error {
 	if loginFlags.CommonFlags.SkipPrompt {
-		return loginDetails, nil
+		return nil
 	}
 
-	err = saml2aws.PromptForLoginDetails(loginDetails, account.Provider)
+	defer beginLoginPrompts(loginFlags.CommonFlags.IdpAccount)()
+
+	err := saml2aws.PromptForLoginDetails(loginDetails, account.Provider)
 	if err != nil {
-		return nil, errors.Wrap(err, "Error occurred accepting input")
+		return errors.Wrap(err, "Error occurred accepting input")
 	}
 
-	return loginDetails, nil
+	return nil
+}
+
+func selectAwsRole(out io.Writer, samlAssertion string, account *cfg.IDPAccount) (*saml2aws.AWSRole, *saml2aws.SAMLAssertion, error) {
+	assertion, err := parseAssertion(out, samlAssertion, account)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	role, err := resolveRole(out, assertion.Roles, samlAssertion, account)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return role, assertion, nil
+}
+
+// parseAssertion decode and parse the saml assertion, returning an error if it doesn't grant any roles
+func parseAssertion(out io.Writer, samlAssertion string, account *cfg.IDPAccount) (*saml2aws.SAMLAssertion, error) {
+	data, err := decodeSAMLAssertion(samlAssertion, account)
+	if err != nil {
+		return nil, err
+	}
+
+	assertion, err := saml2aws.ParseSAMLAssertion(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing saml assertion")
+	}
+
+	if len(assertion.Roles) == 0 {
+		return nil, errors.New("no roles to assume, please check you are permitted to assume roles for the AWS service")
+	}
+
+	printSessionAttributes(out, assertion)
+
+	return assertion, nil
 }
 
-func selectAwsRole(samlAssertion string, account *cfg.IDPAccount) (*saml2aws.AWSRole, error) {
+// printSessionAttributes show the session attributes the IdP asked AWS to apply to the role session
+func printSessionAttributes(out io.Writer, assertion *saml2aws.SAMLAssertion) {
+	if assertion.RoleSessionName != "" {
+		fmt.Fprintln(out, "Role session name:", assertion.RoleSessionName)
+	}
+	if assertion.SourceIdentity != "" {
+		fmt.Fprintln(out, "Source identity:", assertion.SourceIdentity)
+	}
+	for _, tag := range assertion.SessionTags() {
+		fmt.Fprintln(out, "Session tag:", tag)
+	}
+}
+
+// decodeSAMLAssertion decode the assertion, decrypting it if the account has an SP private key configured and
+// verifying its signature if the account has IdP certificates configured
+func decodeSAMLAssertion(samlAssertion string, account *cfg.IDPAccount) ([]byte, error) {
 	data, err := base64.StdEncoding.DecodeString(samlAssertion)
 	if err != nil {
 		return nil, errors.Wrap(err, "error decoding saml assertion")
 	}
 
-	roles, err := saml2aws.ExtractAwsRoles(data)
-	if err != nil {
-		return nil, errors.Wrap(err, "error parsing aws roles")
+	var key *rsa.PrivateKey
+	if saml2aws.DecryptionEnabled(account) {
+		key, err = saml2aws.LoadSPPrivateKey(account)
+		if err != nil {
+			return nil, errors.Wrap(err, "error loading sp private key")
+		}
+	}
+
+	if !saml2aws.SignatureVerificationEnabled(account) {
+		if key == nil {
+			return data, nil
+		}
+
+		data, err = saml2aws.DecryptAssertion(data, key)
+		if err != nil {
+			return nil, errors.Wrap(err, "error decrypting saml assertion")
+		}
+
+		return data, nil
 	}
 
-	if len(roles) == 0 {
-		fmt.Println("No roles to assume")
-		fmt.Println("Please check you are permitted to assume roles for the AWS service")
-		os.Exit(1)
+	certs, err := saml2aws.LoadIDPCertificates(account)
+	if err != nil {
+		return nil, errors.Wrap(err, "error loading idp certificates")
 	}
 
-	awsRoles, err := saml2aws.ParseAWSRoles(roles)
+	data, err = saml2aws.VerifyAssertionSignature(data, certs, key)
 	if err != nil {
-		return nil, errors.Wrap(err, "error parsing aws roles")
+		return nil, errors.Wrap(err, "error verifying saml assertion signature")
 	}
 
-	return resolveRole(awsRoles, samlAssertion, account)
+	return data, nil
 }
 
-func resolveRole(awsRoles []*saml2aws.AWSRole, samlAssertion string, account *cfg.IDPAccount) (*saml2aws.AWSRole, error) {
+func resolveRole(out io.Writer, awsRoles []*saml2aws.AWSRole, samlAssertion string, account *cfg.IDPAccount) (*saml2aws.AWSRole, error) {
 	var role = new(saml2aws.AWSRole)
 
 	if len(awsRoles) == 1 {
 		if account.RoleARN != "" {
-			return saml2aws.LocateRole(awsRoles, account.RoleARN)
+			return locateRole(account.RoleARN, awsRoles, samlAssertion, account)
 		}
 		return awsRoles[0], nil
 	} else if len(awsRoles) == 0 {
 		return nil, errors.New("no roles available")
 	}
 
-	awsAccounts, err := saml2aws.ParseAWSAccounts(samlAssertion)
+	if account.RoleARN != "" {
+		return locateRole(account.RoleARN, awsRoles, samlAssertion, account)
+	}
+
+	awsAccounts, err := resolveAWSAccounts(awsRoles, samlAssertion, account)
 	if err != nil {
-		return nil, errors.Wrap(err, "error parsing aws role accounts")
+		return nil, err
 	}
 	if len(awsAccounts) == 0 {
 		return nil, errors.New("no accounts available")
 	}
 
-	saml2aws.AssignPrincipals(awsRoles, awsAccounts)
-
-	if account.RoleARN != "" {
-		return saml2aws.LocateRole(awsRoles, account.RoleARN)
-	}
-
 	for {
 		role, err = saml2aws.PromptForAWSRoleSelection(awsAccounts)
 		if err == nil {
 			break
 		}
-		fmt.Println("error selecting role, try again")
+		fmt.Fprintln(out, "error selecting role, try again")
 	}
 
 	return role, nil
 }
 
-func loginToStsUsingRole(account *cfg.IDPAccount, role *saml2aws.AWSRole, samlAssertion string) (*awsconfig.AWSCredentials, error) {
+// resolveAWSAccounts group the roles by account, naming the accounts with the configured resolvers
+func resolveAWSAccounts(awsRoles []*saml2aws.AWSRole, samlAssertion string, account *cfg.IDPAccount) ([]*saml2aws.AWSAccount, error) {
+	resolvers, err := buildAccountNameResolvers(account)
+	if err != nil {
+		return nil, errors.Wrap(err, "error building account name resolvers")
+	}
 
-	sess, err := session.NewSession()
+	awsAccounts, err := saml2aws.ResolveAWSAccounts(samlAssertion, awsRoles, resolvers)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create session")
+		return nil, errors.Wrap(err, "error parsing aws role accounts")
 	}
 
-	svc := sts.New(sess)
+	return awsAccounts, nil
+}
+
+func loginToStsUsingRole(out io.Writer, account *cfg.IDPAccount, role *saml2aws.AWSRole, samlAssertion string, idpSessionDuration int64) (*awsconfig.AWSCredentials, error) {
+
+	awsPartition, err := partition.FromRoleARNs(role.RoleARN, role.PrincipalARN)
+	if err != nil {
+		return nil, errors.Wrap(err, "error resolving aws partition of role")
+	}
+
+	_, svc, err := newPartitionSession(awsPartition)
+	if err != nil {
+		return nil, err
+	}
 
 	params := &sts.AssumeRoleWithSAMLInput{
-		PrincipalArn:    aws.String(role.PrincipalARN), // Required
-		RoleArn:         aws.String(role.RoleARN),      // Required
-		SAMLAssertion:   aws.String(samlAssertion),     // Required
-		DurationSeconds: aws.Int64(int64(account.SessionDuration)),
+		PrincipalArn:  aws.String(role.PrincipalARN), // Required
+		RoleArn:       aws.String(role.RoleARN),      // Required
+		SAMLAssertion: aws.String(samlAssertion),     // Required
+	}
+
+	durations, err := sessionDurations(account, idpSessionDuration)
+	if err != nil {
+		return nil, errors.Wrap(err, "error resolving session duration")
 	}
 
-	fmt.Println("Requesting AWS credentials using SAML assertion")
+	fmt.Fprintln(out, "Requesting AWS credentials using SAML assertion")
 
-	resp, err := svc.AssumeRoleWithSAML(params)
+	resp, duration, err := assumeRoleWithSessionDurations(out, svc.AssumeRoleWithSAML, params, durations)
 	if err != nil {
 		return nil, errors.Wrap(err, "error retrieving STS credentials using SAML")
 	}
 
+	if account.SessionDurationAuto {
+		fmt.Fprintln(out, "Granted session duration:", formatSessionDuration(duration))
+	}
+
 	return &awsconfig.AWSCredentials{
 		AWSAccessKey:     aws.StringValue(resp.Credentials.AccessKeyId),
 		AWSSecretKey:     aws.StringValue(resp.Credentials.SecretAccessKey),
@@ -263,12 +564,30 @@ func loginToStsUsingRole(account *cfg.IDPAccount, role *saml2aws.AWSRole, samlAs
 	}, nil
 }
 
-func saveCredentials(awsCreds *awsconfig.AWSCredentials, sharedCreds *awsconfig.CredentialsProvider) error {
+// newPartitionSession build a session in a region of the partition along with an STS client using the regional
+// endpoint, the region from the environment is used when it belongs to the partition
+func newPartitionSession(p *partition.Partition) (*session.Session, *sts.STS, error) {
+	region := p.ResolveRegion()
+
+	sess, err := session.NewSession(aws.NewConfig().WithRegion(region))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create session")
+	}
+
+	return sess, sts.New(sess, aws.NewConfig().WithEndpoint(p.STSEndpoint(region))), nil
+}
+
+func saveCredentials(account *cfg.IDPAccount, awsCreds *awsconfig.AWSCredentials, sharedCreds *awsconfig.CredentialsProvider) error {
 	err := sharedCreds.Save(awsCreds)
 	if err != nil {
 		return errors.Wrap(err, "error saving credentials")
 	}
 
+	err = saveProfileConfig(account, sharedCreds.Profile)
+	if err != nil {
+		return errors.Wrap(err, "error saving aws config")
+	}
+
 	fmt.Println("Logged in as:", awsCreds.PrincipalARN)
 	fmt.Println("")
 	fmt.Println("Your new access key pair has been stored in the AWS configuration")
@@ -277,3 +596,42 @@ func saveCredentials(awsCreds *awsconfig.AWSCredentials, sharedCreds *awsconfig.
 
 	return nil
 }
+
+// saveProfileConfig write the region and output of the idp account to the profile in ~/.aws/config, along with the
+// profiles chained from the profile of the idp account
+func saveProfileConfig(account *cfg.IDPAccount, profile string) error {
+	chained, err := account.ParseChainedProfiles()
+	if err != nil {
+		return err
+	}
+
+	if account.Region == "" && account.Output == "" && len(chained) == 0 {
+		return nil
+	}
+
+	sharedConfig := awsconfig.NewSharedConfig()
+
+	err = sharedConfig.Save(profile, &awsconfig.ConfigProfile{Region: account.Region, Output: account.Output})
+	if err != nil {
+		return err
+	}
+
+	// the chains only hang off the profile of the idp account, not the extra profiles of a multi role login
+	if profile != account.Profile {
+		return nil
+	}
+
+	for _, c := range chained {
+		err = sharedConfig.Save(c.Profile, &awsconfig.ConfigProfile{
+			Region:        account.Region,
+			Output:        account.Output,
+			RoleARN:       c.RoleARN,
+			SourceProfile: profile,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
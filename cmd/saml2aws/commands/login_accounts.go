@@ -0,0 +1,197 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/versent/saml2aws/pkg/awsconfig"
+	"github.com/versent/saml2aws/pkg/cfg"
+	"github.com/versent/saml2aws/pkg/flags"
+	"github.com/versent/saml2aws/pkg/provider"
+)
+
+// concurrentLogins set while loginIdpAccounts runs the logins, so the prompts name the idp account they belong to
+var concurrentLogins bool
+
+// accountLoginResult the outcome of logging in to one of several idp accounts
+type accountLoginResult struct {
+	IdpAccount   string
+	Profile      string
+	PrincipalARN string
+	Expires      time.Time
+	Err          error
+}
+
+// resolveIdpAccountNames expand --all, the comma separated idp accounts and the group:<name> entries of --idp-account
+// into the names of the idp accounts to login to, each listed once
+func resolveIdpAccountNames(loginFlags *flags.LoginExecFlags) ([]string, error) {
+	idpAccount := loginFlags.CommonFlags.IdpAccount
+
+	if !loginFlags.All && !strings.Contains(idpAccount, ",") && !strings.HasPrefix(idpAccount, cfg.GroupSectionPrefix) {
+		return []string{idpAccount}, nil
+	}
+
+	cfgm, err := cfg.NewConfigManager(cfg.DefaultConfigPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load configuration")
+	}
+
+	var entries []string
+	if loginFlags.All {
+		entries, err = cfgm.ListIDPAccounts()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list idp accounts")
+		}
+	} else {
+		entries = strings.Split(idpAccount, ",")
+	}
+
+	names := []string{}
+	seen := map[string]bool{}
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		group := []string{entry}
+		if strings.HasPrefix(entry, cfg.GroupSectionPrefix) {
+			group, err = cfgm.LoadIDPAccountGroup(strings.TrimPrefix(entry, cfg.GroupSectionPrefix))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, name := range group {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, errors.New("no idp accounts to login to")
+	}
+
+	return names, nil
+}
+
+// loginIdpAccounts login to the idp accounts concurrently and print a summary of the results, only the prompts for the
+// login details and roles wait for each other
+func loginIdpAccounts(loginFlags *flags.LoginExecFlags, names []string) error {
+	if loginFlags.CommonFlags.Profile != "" {
+		return errors.New("--profile can't be used when logging in to several idp accounts, set aws_profile for each account instead")
+	}
+
+	// spinners of concurrent requests would draw over each other and over the prompts
+	provider.SetSpinner(false)
+	defer provider.SetSpinner(true)
+
+	concurrentLogins = true
+	defer func() { concurrentLogins = false }()
+
+	results := make([]*accountLoginResult, len(names))
+
+	var wg sync.WaitGroup
+
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			results[i] = loginAccount(loginFlags, name)
+		}(i, name)
+	}
+
+	wg.Wait()
+
+	fmt.Println("")
+
+	err := printLoginResults(os.Stdout, results)
+	if err != nil {
+		return err
+	}
+
+	failed := []string{}
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result.IdpAccount)
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.Errorf("login failed for: %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// loginAccount login to the idp account with its own copy of the flags, reporting the credentials saved to its profile
+func loginAccount(loginFlags *flags.LoginExecFlags, name string) *accountLoginResult {
+	commonFlags := *loginFlags.CommonFlags
+	commonFlags.IdpAccount = name
+
+	accountFlags := *loginFlags
+	accountFlags.CommonFlags = &commonFlags
+
+	result := &accountLoginResult{IdpAccount: name}
+
+	result.Err = loginIdpAccount(&accountFlags)
+	if result.Err != nil {
+		return result
+	}
+
+	account, err := buildIdpAccount(&accountFlags)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Profile = account.Profile
+
+	awsCreds, err := awsconfig.NewSharedCredentials(account.Profile).Load()
+	if err != nil {
+		result.Err = errors.Wrap(err, "error loading credentials")
+		return result
+	}
+
+	result.PrincipalARN = awsCreds.PrincipalARN
+	result.Expires = awsCreds.Expires
+
+	return result
+}
+
+func printLoginResults(w io.Writer, results []*accountLoginResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "IDP ACCOUNT\tPROFILE\tPRINCIPAL\tEXPIRES\tRESULT")
+
+	for _, result := range results {
+		profile, principal, expires := "-", "-", "-"
+		if result.Profile != "" {
+			profile = result.Profile
+		}
+		if result.PrincipalARN != "" {
+			principal = result.PrincipalARN
+		}
+		if !result.Expires.IsZero() {
+			expires = result.Expires.Local().Format(time.RFC3339)
+		}
+
+		status := "ok"
+		if result.Err != nil {
+			status = strings.Replace(result.Err.Error(), "\n", " ", -1)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", result.IdpAccount, profile, principal, expires, status)
+	}
+
+	return tw.Flush()
+}
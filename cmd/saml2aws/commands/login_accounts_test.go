@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/versent/saml2aws/pkg/cfg"
+	"github.com/versent/saml2aws/pkg/flags"
+	"github.com/versent/saml2aws/pkg/prompter"
+)
+
+type passwordPrompter struct {
+	prompter.Prompter
+}
+
+func (p *passwordPrompter) String(pr string, defaultValue string) string {
+	return defaultValue
+}
+
+func (p *passwordPrompter) Password(pr string) string {
+	return "password"
+}
+
+func TestResolveIdpAccountNamesSingle(t *testing.T) {
+	loginFlags := &flags.LoginExecFlags{CommonFlags: &flags.CommonFlags{IdpAccount: "default"}}
+
+	names, err := resolveIdpAccountNames(loginFlags)
+	require.Nil(t, err)
+	require.Equal(t, []string{"default"}, names)
+}
+
+func TestPrintLoginResults(t *testing.T) {
+	expires := time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)
+
+	buf := new(bytes.Buffer)
+	err := printLoginResults(buf, []*accountLoginResult{
+		{IdpAccount: "okta", Profile: "saml", PrincipalARN: "arn:aws:sts::000000000001:assumed-role/Development/wolfeidau", Expires: expires},
+		{IdpAccount: "govcloud", Err: errors.New("error authenticating to IdP")},
+	})
+	require.Nil(t, err)
+
+	assert.Regexp(t, `okta\s+saml\s+arn:aws:sts::000000000001:assumed-role/Development/wolfeidau\s+2020-01-02T03:04:05\S*\s+ok`, buf.String())
+	assert.Regexp(t, `govcloud\s+-\s+-\s+-\s+error authenticating to IdP`, buf.String())
+}
+
+func TestConcurrentLoginsAuthenticateTogether(t *testing.T) {
+	prompter.SetPrompter(&passwordPrompter{})
+	defer prompter.SetPrompter(prompter.NewCli())
+
+	var logins int32
+	bothStarted := make(chan struct{})
+
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			fmt.Fprint(w, `<input name="SAMLResponse" value="PHNhbWxwOlJlc3BvbnNlLz4="/>`)
+			return
+		}
+
+		// each login blocks in the IdP until the other one has also reached it
+		if atomic.AddInt32(&logins, 1) == 2 {
+			close(bothStarted)
+		}
+
+		select {
+		case <-bothStarted:
+		case <-time.After(5 * time.Second):
+			http.Error(w, "the other login never reached the IdP", http.StatusGatewayTimeout)
+			return
+		}
+
+		fmt.Fprintf(w, `<form action="%s/login"><input name="username"/><input name="password"/></form>`, ts.URL)
+	}))
+	defer ts.Close()
+
+	errs := make([]error, 2)
+
+	var wg sync.WaitGroup
+
+	for i, name := range []string{"dev", "prod"} {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+
+			account := &cfg.IDPAccount{URL: ts.URL, Username: name, Provider: "KeyCloak", MFA: "Auto"}
+			loginFlags := &flags.LoginExecFlags{CommonFlags: &flags.CommonFlags{IdpAccount: name, DisableKeychain: true}}
+
+			_, errs[i] = authenticate(ioutil.Discard, account, loginFlags)
+		}(i, name)
+	}
+
+	wg.Wait()
+
+	assert.Nil(t, errs[0])
+	assert.Nil(t, errs[1])
+}
@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/versent/saml2aws/pkg/flock"
+)
+
+const (
+	// DefaultLoginLockDir the directory holding the locks taken while logging in
+	DefaultLoginLockDir = "~/.saml2aws.d/locks"
+
+	// loginLockTimeout how long to wait for a login in another process, long enough to answer an MFA prompt
+	loginLockTimeout = 5 * time.Minute
+)
+
+// lockLogin take the lock on logging in to the idp account and profile so parallel saml2aws processes only prompt
+// once, waited reports if another process held the lock and may have saved fresh credentials in the meantime
+func lockLogin(idpAccountName, profile string) (*flock.Lock, bool, error) {
+	dir, err := homedir.Expand(DefaultLoginLockDir)
+	if err != nil {
+		return nil, false, err
+	}
+
+	filename := filepath.Join(dir, fmt.Sprintf("%s-%s.lock", url.PathEscape(idpAccountName), url.PathEscape(profile)))
+
+	waited := false
+
+	lock, err := flock.AcquireTimeout(filename, loginLockTimeout, func() {
+		waited = true
+		fmt.Fprintf(os.Stderr, "Waiting for another saml2aws process to log in to %s for profile %s...\n", idpAccountName, profile)
+	})
+	if err == flock.ErrTimeout {
+		return nil, false, errors.Errorf("timed out after %v waiting for another saml2aws process to log in, remove %s if no login is running", loginLockTimeout, filename)
+	}
+	if err != nil {
+		return nil, false, errors.Wrap(err, "error locking login")
+	}
+
+	return lock, waited, nil
+}
@@ -0,0 +1,278 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/versent/saml2aws"
+	"github.com/versent/saml2aws/pkg/awsconfig"
+	"github.com/versent/saml2aws/pkg/cfg"
+	"github.com/versent/saml2aws/pkg/flags"
+)
+
+// roleSelection a role ARN or pattern selected for login, along with the profile its credentials are saved to and the
+// session duration requested for it when these have been configured
+type roleSelection struct {
+	Pattern         string
+	Profile         string
+	SessionDuration int
+}
+
+// roleProfile a role in the assertion and the profile its credentials are saved to
+type roleProfile struct {
+	Role            *saml2aws.AWSRole
+	Profile         string
+	SessionDuration int
+}
+
+// buildRoleSelections build the roles for a multi role login from --roles, or the role_profiles:<idp account> section
+// when no role has been chosen for the account, an empty list means a single role login
+func buildRoleSelections(loginFlags *flags.LoginExecFlags, account *cfg.IDPAccount) ([]*roleSelection, error) {
+	cfgm, err := cfg.NewConfigManager(cfg.DefaultConfigPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load configuration")
+	}
+
+	roleProfiles, err := cfgm.LoadRoleProfiles()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load role profiles")
+	}
+
+	accountRoleProfiles, err := cfgm.LoadIDPAccountRoleProfiles(loginFlags.CommonFlags.IdpAccount)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load role profiles")
+	}
+
+	aliases, err := loadRoleAliases()
+	if err != nil {
+		return nil, err
+	}
+
+	return selectRoles(loginFlags.Roles, account, roleProfiles, accountRoleProfiles, aliases), nil
+}
+
+// selectRoles build the selections for the patterns given with --roles, naming their profiles from the role profiles
+// of the idp account then the shared ones, without patterns the role profiles of the idp account are logged in unless
+// the account has a role
+func selectRoles(roles []string, account *cfg.IDPAccount, roleProfiles, accountRoleProfiles map[string]string, aliases map[string]*cfg.RoleAlias) []*roleSelection {
+	selections := []*roleSelection{}
+
+	if len(roles) > 0 {
+		for _, patterns := range roles {
+			for _, pattern := range strings.Split(patterns, ",") {
+				pattern = strings.TrimSpace(pattern)
+				if pattern == "" {
+					continue
+				}
+
+				selection := &roleSelection{Pattern: pattern, Profile: profileForRole(pattern, accountRoleProfiles)}
+				if selection.Profile == "" {
+					selection.Profile = profileForRole(pattern, roleProfiles)
+				}
+				selections = append(selections, expandRoleAlias(selection, aliases))
+			}
+		}
+
+		return selections
+	}
+
+	if account.RoleARN != "" {
+		return selections
+	}
+
+	for _, profile := range sortedProfiles(accountRoleProfiles) {
+		selections = append(selections, expandRoleAlias(&roleSelection{Pattern: accountRoleProfiles[profile], Profile: profile}, aliases))
+	}
+
+	return selections
+}
+
+// profileForRole the first profile, by name, mapped to the role pattern
+func profileForRole(pattern string, roleProfiles map[string]string) string {
+	for _, profile := range sortedProfiles(roleProfiles) {
+		if roleProfiles[profile] == pattern {
+			return profile
+		}
+	}
+
+	return ""
+}
+
+func sortedProfiles(roleProfiles map[string]string) []string {
+	profiles := make([]string, 0, len(roleProfiles))
+	for profile := range roleProfiles {
+		profiles = append(profiles, profile)
+	}
+	sort.Strings(profiles)
+
+	return profiles
+}
+
+// expandRoleAlias replace a role alias with the role it names, taking the profile and session duration of the alias
+// unless the selection already has a profile
+func expandRoleAlias(selection *roleSelection, aliases map[string]*cfg.RoleAlias) *roleSelection {
+	alias, ok := aliases[selection.Pattern]
+	if !ok {
+		return selection
+	}
+
+	selection.Pattern = alias.Role
+	selection.SessionDuration = alias.SessionDuration
+
+	if selection.Profile == "" {
+		selection.Profile = alias.Profile
+	}
+
+	return selection
+}
+
+// matchRoleProfiles locate the roles in the assertion for each selection, roles selected by a pattern without a
+// profile are saved to a profile named after the account and role
+func matchRoleProfiles(awsRoles []*saml2aws.AWSRole, samlAssertion string, account *cfg.IDPAccount, selections []*roleSelection) ([]*roleProfile, error) {
+	matches := []*roleProfile{}
+	seen := map[string]bool{}
+
+	for _, selection := range selections {
+		roles, err := locateRoles(selection.Pattern, awsRoles, samlAssertion, account)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(roles) == 0 {
+			return nil, errors.Errorf("no role in the saml assertion matches %s", selection.Pattern)
+		}
+
+		if selection.Profile != "" && len(roles) > 1 {
+			return nil, errors.Errorf("%s matches %d roles but profile %s can only hold one", selection.Pattern, len(roles), selection.Profile)
+		}
+
+		for _, role := range roles {
+			if seen[role.RoleARN] {
+				continue
+			}
+			seen[role.RoleARN] = true
+
+			profile := selection.Profile
+			if profile == "" {
+				profile = defaultRoleProfile(role)
+			}
+
+			matches = append(matches, &roleProfile{Role: role, Profile: profile, SessionDuration: selection.SessionDuration})
+		}
+	}
+
+	return matches, nil
+}
+
+// defaultRoleProfile name the profile of a role after its account id and role name, for example 000000000001-ReadOnly
+func defaultRoleProfile(role *saml2aws.AWSRole) string {
+	accountID, err := saml2aws.AccountIDFromARN(role.RoleARN)
+	if err != nil {
+		return saml2aws.RoleNameFromARN(role.RoleARN)
+	}
+
+	return accountID + "-" + saml2aws.RoleNameFromARN(role.RoleARN)
+}
+
+// roleProfilesExpired returns true if any of the selected profiles needs new credentials, selections without a profile
+// aren't known until the assertion has been parsed so always need a login
+func roleProfilesExpired(selections []*roleSelection) bool {
+	for _, selection := range selections {
+		if selection.Profile == "" || awsconfig.NewSharedCredentials(selection.Profile).Expired() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkChainProfiles the credentials of an assume_chain hop are saved to one profile, which can't hold the chains
+// started from several roles
+func checkChainProfiles(account *cfg.IDPAccount, roles int) error {
+	if roles < 2 {
+		return nil
+	}
+
+	hops, err := account.ParseAssumeChain()
+	if err != nil {
+		return errors.Wrap(err, "error parsing assume_chain")
+	}
+
+	for _, hop := range hops {
+		if hop.Profile != "" {
+			return errors.Errorf("assume_chain saves %s to profile %s, which can't hold the chains of %d roles, remove the profile of the hop or log in to one role", hop.RoleARN, hop.Profile, roles)
+		}
+	}
+
+	return nil
+}
+
+// loginToRoles assume each selected role with the one saml assertion, followed by the roles of assume_chain, and save
+// the credentials to the profile of each
+func loginToRoles(idpAccountName string, account *cfg.IDPAccount, samlAssertion string, selections []*roleSelection) error {
+	logger := logrus.WithField("command", "login")
+
+	assertion, err := parseAssertion(os.Stdout, samlAssertion, account)
+	if err != nil {
+		return err
+	}
+
+	matches, err := matchRoleProfiles(assertion.Roles, samlAssertion, account, selections)
+	if err != nil {
+		return errors.Wrap(err, "error selecting roles")
+	}
+
+	err = checkChainProfiles(account, len(matches))
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+
+	for _, match := range matches {
+		fmt.Println("Selected role:", match.Role.RoleARN)
+
+		roleAccount := *account
+		if match.SessionDuration != 0 {
+			roleAccount.SessionDuration = match.SessionDuration
+			roleAccount.SessionDurationAuto = false
+		}
+
+		awsCreds, err := loginToStsUsingRole(os.Stdout, &roleAccount, match.Role, samlAssertion, assertion.SessionDuration)
+		if err != nil {
+			// carry on so one role the user can no longer assume doesn't block the others
+			logger.WithError(err).WithField("role", match.Role.RoleARN).Debug("error logging into aws role")
+			fmt.Printf("Failed to login to %s: %v\n\n", match.Role.RoleARN, err)
+			failed++
+			continue
+		}
+
+		awsCreds.RoleSessionName = assertion.RoleSessionName
+		awsCreds.SourceIdentity = assertion.SourceIdentity
+		awsCreds.IdpAccount = idpAccountName
+
+		awsCreds, err = assumeChain(os.Stdout, &roleAccount, awsCreds, true)
+		if err != nil {
+			logger.WithError(err).WithField("role", match.Role.RoleARN).Debug("error assuming roles of assume_chain")
+			fmt.Printf("Failed to assume the roles of assume_chain from %s: %v\n\n", match.Role.RoleARN, err)
+			failed++
+			continue
+		}
+
+		err = saveCredentials(account, awsCreds, awsconfig.NewSharedCredentials(match.Profile))
+		if err != nil {
+			return err
+		}
+		fmt.Println("")
+	}
+
+	if failed > 0 {
+		return errors.Errorf("failed to login to %d of %d roles", failed, len(matches))
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/versent/saml2aws"
+	"github.com/versent/saml2aws/pkg/cfg"
+)
+
+func TestMatchRoleProfiles(t *testing.T) {
+	awsRoles := []*saml2aws.AWSRole{
+		{RoleARN: "arn:aws:iam::000000000001:role/Development"},
+		{RoleARN: "arn:aws:iam::000000000001:role/ReadOnly"},
+		{RoleARN: "arn:aws:iam::000000000002:role/ReadOnly"},
+	}
+
+	matches, err := matchRoleProfiles(awsRoles, "", nil, []*roleSelection{
+		{Pattern: "arn:aws:iam::000000000001:role/Development", Profile: "dev"},
+		{Pattern: "*:role/ReadOnly"},
+		{Pattern: "arn:aws:iam::000000000001:role/ReadOnly"},
+	})
+	require.Nil(t, err)
+	require.Len(t, matches, 3)
+
+	assert.Equal(t, "dev", matches[0].Profile)
+	assert.Equal(t, awsRoles[0], matches[0].Role)
+	assert.Equal(t, "000000000001-ReadOnly", matches[1].Profile)
+	assert.Equal(t, awsRoles[1], matches[1].Role)
+	assert.Equal(t, "000000000002-ReadOnly", matches[2].Profile)
+	assert.Equal(t, awsRoles[2], matches[2].Role)
+
+	_, err = matchRoleProfiles(awsRoles, "", nil, []*roleSelection{{Pattern: "*:role/ReadOnly", Profile: "ro"}})
+	assert.Error(t, err)
+
+	_, err = matchRoleProfiles(awsRoles, "", nil, []*roleSelection{{Pattern: "*:role/Admin"}})
+	assert.Error(t, err)
+}
+
+func TestSelectRoles(t *testing.T) {
+	roleProfiles := map[string]string{
+		"dev":     "arn:aws:iam::000000000002:role/Development",
+		"prod-ro": "arn:aws:iam::000000000001:role/ReadOnly",
+	}
+	accountRoleProfiles := map[string]string{
+		"work-dev": "arn:aws:iam::000000000002:role/Development",
+		"work-ro":  "*:role/ReadOnly",
+	}
+
+	// the shared role profiles only name the profiles of roles given with --roles
+	selections := selectRoles(nil, &cfg.IDPAccount{}, roleProfiles, nil, nil)
+	assert.Empty(t, selections)
+
+	selections = selectRoles([]string{"arn:aws:iam::000000000001:role/ReadOnly,arn:aws:iam::000000000002:role/Development"}, &cfg.IDPAccount{}, roleProfiles, accountRoleProfiles, nil)
+	assert.Equal(t, []*roleSelection{
+		{Pattern: "arn:aws:iam::000000000001:role/ReadOnly", Profile: "prod-ro"},
+		{Pattern: "arn:aws:iam::000000000002:role/Development", Profile: "work-dev"},
+	}, selections)
+
+	selections = selectRoles(nil, &cfg.IDPAccount{}, roleProfiles, accountRoleProfiles, nil)
+	assert.Equal(t, []*roleSelection{
+		{Pattern: "arn:aws:iam::000000000002:role/Development", Profile: "work-dev"},
+		{Pattern: "*:role/ReadOnly", Profile: "work-ro"},
+	}, selections)
+
+	selections = selectRoles(nil, &cfg.IDPAccount{RoleARN: "arn:aws:iam::000000000002:role/Development"}, roleProfiles, accountRoleProfiles, nil)
+	assert.Empty(t, selections)
+}
+
+func TestExpandRoleAlias(t *testing.T) {
+	aliases := map[string]*cfg.RoleAlias{
+		"prod-ro": {Role: "production/ReadOnly", Profile: "prod-ro", SessionDuration: 28800},
+	}
+
+	selection := expandRoleAlias(&roleSelection{Pattern: "prod-ro"}, aliases)
+	assert.Equal(t, &roleSelection{Pattern: "production/ReadOnly", Profile: "prod-ro", SessionDuration: 28800}, selection)
+
+	selection = expandRoleAlias(&roleSelection{Pattern: "prod-ro", Profile: "readonly"}, aliases)
+	assert.Equal(t, "readonly", selection.Profile)
+
+	selection = expandRoleAlias(&roleSelection{Pattern: "*:role/ReadOnly"}, aliases)
+	assert.Equal(t, &roleSelection{Pattern: "*:role/ReadOnly"}, selection)
+}
+
+func TestCheckChainProfiles(t *testing.T) {
+	account := &cfg.IDPAccount{AssumeChain: "arn:aws:iam::000000000002:role/Spoke profile=spoke, arn:aws:iam::000000000003:role/Admin"}
+
+	assert.Nil(t, checkChainProfiles(account, 1))
+	assert.Error(t, checkChainProfiles(account, 2))
+
+	account.AssumeChain = "arn:aws:iam::000000000002:role/Spoke, arn:aws:iam::000000000003:role/Admin"
+	assert.Nil(t, checkChainProfiles(account, 2))
+}
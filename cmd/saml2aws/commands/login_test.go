@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"io/ioutil"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -21,7 +22,7 @@ func TestResolveLoginDetailsWithFlags(t *testing.T) {
 		Provider: "Ping",
 		Username: "wolfeidau",
 	}
-	loginDetails, err := resolveLoginDetails(idpa, loginFlags)
+	loginDetails, err := resolveLoginDetails(ioutil.Discard, idpa, loginFlags)
 
 	assert.Empty(t, err)
 	assert.Equal(t, &creds.LoginDetails{Username: "wolfeidau", Password: "testtestlol", URL: "https://id.example.com", MFAToken: "123456"}, loginDetails)
@@ -39,7 +40,7 @@ func TestResolveRoleSingleEntry(t *testing.T) {
 		adminRole,
 	}
 
-	got, err := resolveRole(awsRoles, "", cfg.NewIDPAccount())
+	got, err := resolveRole(ioutil.Discard, awsRoles, "", cfg.NewIDPAccount())
 	assert.Empty(t, err)
 	assert.Equal(t, got, adminRole)
 }
@@ -0,0 +1,229 @@
+package commands
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/versent/saml2aws"
+	"github.com/versent/saml2aws/helper/credentials"
+	"github.com/versent/saml2aws/pkg/awsconfig"
+	"github.com/versent/saml2aws/pkg/cfg"
+	"github.com/versent/saml2aws/pkg/cookiejar"
+	"github.com/versent/saml2aws/pkg/flags"
+	"github.com/versent/saml2aws/pkg/provider"
+	"github.com/versent/saml2aws/pkg/samlcache"
+)
+
+// Logout remove the credentials of the profile, the saved password, the cached assertion and cookies, optionally ending
+// the IdP session with SAML single logout
+func Logout(logoutFlags *flags.LogoutFlags) error {
+
+	logger := logrus.WithField("command", "logout")
+
+	loginFlags := logoutFlags.LoginExecFlags
+	idpAccountName := loginFlags.CommonFlags.IdpAccount
+
+	account, err := buildIdpAccount(loginFlags)
+	if err != nil {
+		return errors.Wrap(err, "error building login details")
+	}
+
+	// the cached assertion names the IdP session to end and the cached cookies carry it, so both are read before the
+	// caches are cleared
+	var assertion *saml2aws.SAMLAssertion
+	var jar *cookiejar.Jar
+	if logoutFlags.SLO {
+		assertion, err = loadCachedAssertion(account, idpAccountName)
+		if err != nil {
+			logger.WithError(err).Debug("unable to load cached saml assertion")
+		}
+
+		jar, err = loadCachedCookies(account, loginFlags)
+		if err != nil {
+			logger.WithError(err).Debug("unable to load cached cookies")
+		}
+	}
+
+	sharedCreds := awsconfig.NewSharedCredentials(account.Profile)
+
+	err = sharedCreds.Delete()
+	if err != nil {
+		return errors.Wrap(err, "error removing credentials")
+	}
+	fmt.Println("Removed credentials for profile", account.Profile)
+
+	// the profiles of --roles, role_profiles and assume_chain hops
+	profiles, err := sharedCreds.DeleteIdpAccountProfiles(idpAccountName)
+	if err != nil {
+		return errors.Wrap(err, "error removing credentials")
+	}
+	for _, profile := range profiles {
+		fmt.Println("Removed credentials for profile", profile)
+	}
+
+	if !loginFlags.CommonFlags.DisableKeychain && credentials.SupportsStorage() {
+		err = credentials.DeleteCredentials(account.URL, account.Provider)
+		if err != nil {
+			return errors.Wrap(err, "error removing saved password")
+		}
+		fmt.Println("Removed saved password for", account.URL)
+
+		err = clearCredentialProcessCache(idpAccountName)
+		if err != nil {
+			return errors.Wrap(err, "error clearing credential-process cache")
+		}
+	}
+
+	err = clearAssertionCache(idpAccountName, loginFlags.CommonFlags.DisableKeychain)
+	if err != nil {
+		return errors.Wrap(err, "error clearing assertion cache")
+	}
+
+	var sloErr error
+	if logoutFlags.SLO {
+		sloErr = singleLogout(account, assertion, jar)
+	}
+
+	// the cookies are cleared even when single logout failed, the next login then starts a new IdP session
+	err = clearCookieCache(idpAccountName, loginFlags.CommonFlags.DisableKeychain)
+	if err != nil {
+		return errors.Wrap(err, "error clearing cookie cache")
+	}
+
+	return sloErr
+}
+
+// loadCachedAssertion load and parse the assertion cached for the idp account
+func loadCachedAssertion(account *cfg.IDPAccount, idpAccountName string) (*saml2aws.SAMLAssertion, error) {
+	store, err := samlcache.NewStore(account.AssertionCache)
+	if err != nil || store == nil {
+		return nil, err
+	}
+
+	entry, err := store.Load(idpAccountName)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := decodeSAMLAssertion(entry.SAMLResponse, account)
+	if err != nil {
+		return nil, err
+	}
+
+	return saml2aws.ParseSAMLAssertion(data)
+}
+
+// loadCachedCookies build a jar holding the cookies cached for the idp account, so the LogoutRequest reaches the IdP
+// in the session it ends
+func loadCachedCookies(account *cfg.IDPAccount, loginFlags *flags.LoginExecFlags) (*cookiejar.Jar, error) {
+	store, err := cookieCacheStore(account, loginFlags)
+	if err != nil || store == nil {
+		return nil, err
+	}
+
+	jar, _, err := restoreCookieJar(store, loginFlags.CommonFlags.IdpAccount, account.URL, account.Username)
+
+	return jar, err
+}
+
+// clearAssertionCache remove the cached assertion from every store, the cache mode may have changed since it was saved
+func clearAssertionCache(idpAccountName string, disableKeychain bool) error {
+	logger := logrus.WithField("command", "logout")
+
+	fileStore, err := samlcache.NewStore(samlcache.ModeFile)
+	if err != nil {
+		return err
+	}
+
+	err = fileStore.Delete(idpAccountName)
+	if err != nil {
+		return err
+	}
+
+	if disableKeychain || !credentials.SupportsStorage() {
+		return nil
+	}
+
+	keychainStore, err := samlcache.NewStore(samlcache.ModeKeychain)
+	if err != nil {
+		return err
+	}
+
+	err = keychainStore.Delete(idpAccountName)
+	if err != nil {
+		// the keychain may never have held an assertion
+		logger.WithError(err).Debug("unable to remove cached saml assertion from keychain")
+	}
+
+	return nil
+}
+
+// singleLogout send a LogoutRequest for the session the assertion was issued in to the IdP, falling back to the
+// username when there is no cached assertion to take the subject from, the request carries the cookies of the session
+// when the jar isn't nil
+func singleLogout(account *cfg.IDPAccount, assertion *saml2aws.SAMLAssertion, jar *cookiejar.Jar) error {
+	sloURL, err := saml2aws.LocateSLOEndpoint(account)
+	if err != nil {
+		return err
+	}
+
+	subject := saml2aws.SAMLSubject{NameID: account.Username}
+	sessionIndex := ""
+
+	if assertion != nil {
+		subject = assertion.Subject
+		if assertion.AuthnStatement != nil {
+			sessionIndex = assertion.AuthnStatement.SessionIndex
+		}
+	}
+
+	if subject.NameID == "" {
+		return errors.New("unable to build logout request, no cached assertion or username to name the subject")
+	}
+
+	request, err := saml2aws.BuildLogoutRequest(account.AmazonWebservicesURN, sloURL, subject, sessionIndex, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "error building logout request")
+	}
+
+	var key *rsa.PrivateKey
+	if saml2aws.DecryptionEnabled(account) {
+		key, err = saml2aws.LoadSPPrivateKey(account)
+		if err != nil {
+			return errors.Wrap(err, "error loading sp private key")
+		}
+	}
+
+	redirectURL, err := saml2aws.LogoutRedirectURL(sloURL, request, key)
+	if err != nil {
+		return errors.Wrap(err, "error encoding logout request")
+	}
+
+	tr, err := provider.NewTransport(account)
+	if err != nil {
+		return errors.Wrap(err, "error building http transport")
+	}
+
+	client := &http.Client{Transport: tr}
+	if jar != nil {
+		client.Jar = jar
+	}
+
+	res, err := client.Get(redirectURL)
+	if err != nil {
+		return errors.Wrap(err, "error sending logout request")
+	}
+	res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return errors.Errorf("error sending logout request, IdP returned %s", res.Status)
+	}
+
+	fmt.Println("Ended the IdP session at", sloURL)
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/versent/saml2aws/pkg/awsconfig"
+	"github.com/versent/saml2aws/pkg/cookiecache"
+	"github.com/versent/saml2aws/pkg/flags"
+	"github.com/versent/saml2aws/pkg/provider"
+)
+
+func TestLogoutSingleLogoutWithCachedCookies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logout")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	previousHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", previousHome)
+	os.Setenv("HOME", dir)
+
+	previousCredentialsFile := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	defer os.Setenv("AWS_SHARED_CREDENTIALS_FILE", previousCredentialsFile)
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+
+	homedir.DisableCache = true
+	defer func() { homedir.DisableCache = false }()
+
+	sessionCookie := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("idp_session"); err == nil {
+			sessionCookie = cookie.Value
+		}
+	}))
+	defer ts.Close()
+
+	err = ioutil.WriteFile(filepath.Join(dir, ".saml2aws"), []byte(fmt.Sprintf(`[default]
+url          = %s
+slo_url      = %s/slo
+username     = wolfeidau
+provider     = KeyCloak
+mfa          = Auto
+aws_profile  = saml
+cookie_cache = file
+`, ts.URL, ts.URL)), 0600)
+	require.Nil(t, err)
+
+	jar, err := provider.NewCookieJar()
+	require.Nil(t, err)
+
+	u, err := url.Parse(ts.URL)
+	require.Nil(t, err)
+	jar.SetCookies(u, []*http.Cookie{{Name: "idp_session", Value: "abc123"}})
+
+	buf := new(bytes.Buffer)
+	require.Nil(t, jar.Save(buf))
+
+	store, err := cookiecache.NewStore(cookiecache.ModeFile)
+	require.Nil(t, err)
+	require.Nil(t, store.Save("default", &cookiecache.Entry{URL: ts.URL, Username: "wolfeidau", Cookies: buf.Bytes()}))
+
+	for profile, idpAccount := range map[string]string{"saml": "default", "000000000001-ReadOnly": "default", "chained": "default", "other": "other"} {
+		err = awsconfig.NewSharedCredentials(profile).Save(&awsconfig.AWSCredentials{AWSAccessKey: "AKIA", IdpAccount: idpAccount})
+		require.Nil(t, err)
+	}
+
+	err = Logout(&flags.LogoutFlags{
+		LoginExecFlags: &flags.LoginExecFlags{CommonFlags: &flags.CommonFlags{IdpAccount: "default", DisableKeychain: true}},
+		SLO:            true,
+	})
+	require.Nil(t, err)
+
+	assert.Equal(t, "abc123", sessionCookie)
+
+	_, err = store.Load("default")
+	assert.Equal(t, cookiecache.ErrNotFound, err)
+
+	for _, profile := range []string{"saml", "000000000001-ReadOnly", "chained"} {
+		_, err = awsconfig.NewSharedCredentials(profile).Load()
+		assert.Equal(t, awsconfig.ErrCredentialsNotFound, err, profile)
+	}
+
+	_, err = awsconfig.NewSharedCredentials("other").Load()
+	assert.Nil(t, err)
+}
@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"github.com/pkg/errors"
+	"github.com/versent/saml2aws"
+	"github.com/versent/saml2aws/pkg/cfg"
+	"github.com/versent/saml2aws/pkg/flags"
+)
+
+// loadRoleAliases load the named roles from the roles section of the configuration
+func loadRoleAliases() (map[string]*cfg.RoleAlias, error) {
+	cfgm, err := cfg.NewConfigManager(cfg.DefaultConfigPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load configuration")
+	}
+
+	aliases, err := cfgm.LoadRoleAliases()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load role aliases")
+	}
+
+	return aliases, nil
+}
+
+// applyRoleAlias replace a role alias with the role it names, the profile and session duration of the alias are used
+// unless they were given on the command line
+func applyRoleAlias(account *cfg.IDPAccount, commonFlags *flags.CommonFlags) error {
+	if account.RoleARN == "" {
+		return nil
+	}
+
+	aliases, err := loadRoleAliases()
+	if err != nil {
+		return err
+	}
+
+	alias, ok := aliases[account.RoleARN]
+	if !ok {
+		return nil
+	}
+
+	account.RoleARN = alias.Role
+
+	if alias.Profile != "" && commonFlags.Profile == "" {
+		account.Profile = alias.Profile
+	}
+
+	if alias.SessionDuration != 0 && commonFlags.SessionDuration == 0 && !commonFlags.SessionDurationAuto {
+		account.SessionDuration = alias.SessionDuration
+		account.SessionDurationAuto = false
+	}
+
+	account.ApplyPartitionURN()
+
+	return nil
+}
+
+// locateRoles find the roles in the assertion matching the pattern, the accounts are only named for account/role
+// patterns as naming them can take a round trip to AWS
+func locateRoles(pattern string, awsRoles []*saml2aws.AWSRole, samlAssertion string, account *cfg.IDPAccount) ([]*saml2aws.AWSRole, error) {
+	if !saml2aws.IsAccountRolePattern(pattern) {
+		return saml2aws.MatchRoles(awsRoles, pattern)
+	}
+
+	awsAccounts, err := resolveAWSAccounts(awsRoles, samlAssertion, account)
+	if err != nil {
+		return nil, err
+	}
+
+	return saml2aws.MatchAccountRoles(awsAccounts, pattern)
+}
+
+// locateRole find the one role in the assertion matching the pattern
+func locateRole(pattern string, awsRoles []*saml2aws.AWSRole, samlAssertion string, account *cfg.IDPAccount) (*saml2aws.AWSRole, error) {
+	roles, err := locateRoles(pattern, awsRoles, samlAssertion, account)
+	if err != nil {
+		return nil, err
+	}
+
+	return saml2aws.ExpectOneRole(roles, pattern)
+}
@@ -1,40 +1,79 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"text/template"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/versent/saml2aws/pkg/awsconfig"
+	"github.com/versent/saml2aws/pkg/cfg"
 	"github.com/versent/saml2aws/pkg/flags"
+	"github.com/versent/saml2aws/pkg/partition"
 )
 
-const bashTmpl = `export AWS_ACCESS_KEY_ID="{{ .AWSAccessKey }}"
-export AWS_SECRET_ACCESS_KEY="{{ .AWSSecretKey }}"
-export AWS_SESSION_TOKEN="{{ .AWSSessionToken }}"
-export AWS_SECURITY_TOKEN="{{ .AWSSecurityToken }}"
-export SAML2AWS_PROFILE="{{ .ProfileName }}"
-`
-
-const fishTmpl = `set -gx AWS_ACCESS_KEY_ID {{ .AWSAccessKey }}
-set -gx AWS_SECRET_ACCESS_KEY {{ .AWSSecretKey }}
-set -gx AWS_SESSION_TOKEN {{ .AWSSessionToken }}
-set -gx AWS_SECURITY_TOKEN {{ .AWSSecurityToken }}
-set -gx SAML2AWS_PROFILE {{ .ProfileName }}
-"
-`
-
-const powershellTmpl = `$env:AWS_ACCESS_KEY_ID='{{ .AWSAccessKey }}'
-$env:AWS_SECRET_ACCESS_KEY='{{ .AWSSecretKey }}'
-$env:AWS_SESSION_TOKEN='{{ .AWSSessionToken }}'
-$env:AWS_SECURITY_TOKEN='{{ .AWSSecurityToken }}'
-$env:SAML2AWS_PROFILE='{{ .ProfileName }}'
-`
-
-// Script will emit a bash script that will export environment variables
-func Script(execFlags *flags.LoginExecFlags, shell string) error {
+// envVar a variable exported by the script
+type envVar struct {
+	Name  string
+	Value string
+}
+
+// scriptFormat writes the variables in the syntax of a shell or file format, unset is nil when the format can't clear
+// variables
+type scriptFormat struct {
+	export func(w io.Writer, vars []envVar, awsCreds *awsconfig.AWSCredentials) error
+	unset  func(w io.Writer, vars []envVar) error
+}
+
+// scriptFormats the formats supported by the script command, keyed by the name given to --shell
+var scriptFormats = map[string]scriptFormat{
+	"bash":               lineFormat(`export {{ .Name }}="{{ .Value }}"`, `unset {{ .Name }}`),
+	"fish":               lineFormat(`set -gx {{ .Name }} {{ .Value }}`, `set -e {{ .Name }}`),
+	"powershell":         lineFormat(`$env:{{ .Name }}='{{ .Value }}'`, `Remove-Item Env:\{{ .Name }} -ErrorAction SilentlyContinue`),
+	"nushell":            lineFormat(`$env.{{ .Name }} = "{{ .Value }}"`, `hide-env -i {{ .Name }}`),
+	"cmd":                lineFormat(`set {{ .Name }}={{ .Value }}`, `set {{ .Name }}=`),
+	"dotenv":             lineFormat(`{{ .Name }}="{{ .Value }}"`, ""),
+	"docker":             lineFormat(`{{ .Name }}={{ .Value }}`, ""),
+	"github-actions":     lineFormat(`{{ .Name }}={{ .Value }}`, `{{ .Name }}=`),
+	"json":               {export: exportJSON},
+	"credential-process": {export: exportCredentialProcess},
+}
+
+// ScriptFormats return the names of the supported script formats
+func ScriptFormats() []string {
+	names := make([]string, 0, len(scriptFormats))
+	for name := range scriptFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Script will emit a script that will export environment variables, or clear them again when unset is true
+func Script(execFlags *flags.LoginExecFlags, shell string, unset bool) error {
+	format, ok := scriptFormats[shell]
+	if !ok {
+		return errors.Errorf("unsupported script format: %s", shell)
+	}
+
+	if unset {
+		if format.unset == nil {
+			return errors.Errorf("the %s format doesn't support --unset", shell)
+		}
+
+		err := format.unset(os.Stdout, buildScriptVars(&cfg.IDPAccount{}, &awsconfig.AWSCredentials{}))
+		if err != nil {
+			return errors.Wrap(err, "error generating template")
+		}
+
+		return nil
+	}
+
 	account, err := buildIdpAccount(execFlags)
 	if err != nil {
 		return errors.Wrap(err, "error building login details")
@@ -63,16 +102,7 @@ func Script(execFlags *flags.LoginExecFlags, shell string) error {
 		return errors.New("error aws credentials have expired")
 	}
 
-	// annoymous struct to pass to template
-	data := struct {
-		ProfileName string
-		*awsconfig.AWSCredentials
-	}{
-		account.Profile,
-		awsCreds,
-	}
-
-	err = buildTmpl(shell, data)
+	err = format.export(os.Stdout, buildScriptVars(account, awsCreds), awsCreds)
 	if err != nil {
 		return errors.Wrap(err, "error generating template")
 	}
@@ -80,23 +110,88 @@ func Script(execFlags *flags.LoginExecFlags, shell string) error {
 	return nil
 }
 
-func buildTmpl(shell string, data interface{}) error {
-	t := template.New("envvar_script")
+// buildScriptVars build the variables exported by the script, the region is taken from the idp account, then from the
+// environment when it is in the partition of the credentials
+func buildScriptVars(account *cfg.IDPAccount, awsCreds *awsconfig.AWSCredentials) []envVar {
+	region := account.Region
+	if region == "" {
+		awsPartition, err := partition.FromARN(awsCreds.PrincipalARN)
+		if err != nil {
+			awsPartition = partition.AWS
+		}
+
+		region = awsPartition.ResolveRegion()
+	}
 
-	var err error
+	expiration := ""
+	if !awsCreds.Expires.IsZero() {
+		expiration = awsCreds.Expires.UTC().Format(time.RFC3339)
+	}
 
-	switch shell {
-	case "bash":
-		t, err = t.Parse(bashTmpl)
-	case "powershell":
-		t, err = t.Parse(powershellTmpl)
-	case "fish":
-		t, err = t.Parse(fishTmpl)
+	return []envVar{
+		{"AWS_ACCESS_KEY_ID", awsCreds.AWSAccessKey},
+		{"AWS_SECRET_ACCESS_KEY", awsCreds.AWSSecretKey},
+		{"AWS_SESSION_TOKEN", awsCreds.AWSSessionToken},
+		{"AWS_SECURITY_TOKEN", awsCreds.AWSSecurityToken},
+		{"AWS_CREDENTIAL_EXPIRATION", expiration},
+		{"AWS_REGION", region},
+		{"SAML2AWS_PRINCIPAL_ARN", awsCreds.PrincipalARN},
+		{"SAML2AWS_PROFILE", account.Profile},
 	}
+}
 
+// lineFormat build a format writing one line per variable, without an unset template the format can't clear variables
+func lineFormat(exportTmpl, unsetTmpl string) scriptFormat {
+	format := scriptFormat{
+		export: func(w io.Writer, vars []envVar, awsCreds *awsconfig.AWSCredentials) error {
+			return writeLines(w, exportTmpl, vars)
+		},
+	}
+
+	if unsetTmpl != "" {
+		format.unset = func(w io.Writer, vars []envVar) error {
+			return writeLines(w, unsetTmpl, vars)
+		}
+	}
+
+	return format
+}
+
+func writeLines(w io.Writer, lineTmpl string, vars []envVar) error {
+	t, err := template.New("envvar_script").Parse(lineTmpl + "\n")
 	if err != nil {
 		return err
 	}
 
-	return t.Execute(os.Stdout, data)
+	for _, v := range vars {
+		err = t.Execute(w, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func exportJSON(w io.Writer, vars []envVar, awsCreds *awsconfig.AWSCredentials) error {
+	values := map[string]string{}
+	for _, v := range vars {
+		values[v.Name] = v.Value
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(values)
+}
+
+func exportCredentialProcess(w io.Writer, vars []envVar, awsCreds *awsconfig.AWSCredentials) error {
+	data, err := awsCreds.CredentialProcessJSON()
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, string(data))
+
+	return err
 }
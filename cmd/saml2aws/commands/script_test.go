@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/versent/saml2aws/pkg/awsconfig"
+	"github.com/versent/saml2aws/pkg/cfg"
+)
+
+func TestScriptFormats(t *testing.T) {
+	awsCreds := &awsconfig.AWSCredentials{
+		AWSAccessKey:     "AKIA",
+		AWSSecretKey:     "secret",
+		AWSSessionToken:  "token",
+		AWSSecurityToken: "token",
+		PrincipalARN:     "arn:aws-cn:sts::000000000001:assumed-role/Development/wolfeidau",
+		Expires:          time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC),
+	}
+	vars := buildScriptVars(&cfg.IDPAccount{Profile: "saml"}, awsCreds)
+
+	tests := []struct {
+		format string
+		line   string
+	}{
+		{"bash", `export AWS_CREDENTIAL_EXPIRATION="2020-01-01T01:00:00Z"`},
+		{"fish", "set -gx AWS_ACCESS_KEY_ID AKIA\n"},
+		{"powershell", `$env:AWS_REGION='cn-north-1'`},
+		{"nushell", `$env.SAML2AWS_PROFILE = "saml"`},
+		{"cmd", "set AWS_SECRET_ACCESS_KEY=secret"},
+		{"dotenv", `AWS_SESSION_TOKEN="token"`},
+		{"docker", "SAML2AWS_PRINCIPAL_ARN=arn:aws-cn:sts::000000000001:assumed-role/Development/wolfeidau"},
+		{"github-actions", "AWS_REGION=cn-north-1"},
+		{"json", `"AWS_ACCESS_KEY_ID": "AKIA"`},
+		{"credential-process", `"Expiration":"2020-01-01T01:00:00Z"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			require.Nil(t, scriptFormats[tt.format].export(buf, vars, awsCreds))
+			assert.Contains(t, buf.String(), tt.line)
+			assert.NotContains(t, buf.String(), "\n\"\n")
+		})
+	}
+
+	assert.Len(t, ScriptFormats(), len(tests))
+}
+
+func TestScriptRegionFromAccount(t *testing.T) {
+	awsCreds := &awsconfig.AWSCredentials{PrincipalARN: "arn:aws:sts::000000000001:assumed-role/Development/wolfeidau"}
+
+	vars := buildScriptVars(&cfg.IDPAccount{Profile: "saml", Region: "ap-southeast-2"}, awsCreds)
+	assert.Contains(t, vars, envVar{"AWS_REGION", "ap-southeast-2"})
+}
+
+func TestScriptUnset(t *testing.T) {
+	vars := buildScriptVars(&cfg.IDPAccount{}, &awsconfig.AWSCredentials{})
+
+	buf := new(bytes.Buffer)
+	require.Nil(t, scriptFormats["bash"].unset(buf, vars))
+	assert.Contains(t, buf.String(), "unset AWS_ACCESS_KEY_ID\n")
+
+	buf.Reset()
+	require.Nil(t, scriptFormats["fish"].unset(buf, vars))
+	assert.Contains(t, buf.String(), "set -e AWS_SESSION_TOKEN\n")
+
+	assert.Nil(t, scriptFormats["dotenv"].unset)
+	assert.Nil(t, scriptFormats["json"].unset)
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/versent/saml2aws/pkg/awsconfig"
+	"github.com/versent/saml2aws/pkg/cfg"
+	"github.com/versent/saml2aws/pkg/credserver"
+	"github.com/versent/saml2aws/pkg/flags"
+)
+
+// Serve answer the ECS container credentials protocol on a local address, refreshing the credentials before they expire
+func Serve(serveFlags *flags.ServeFlags) error {
+
+	loginFlags := serveFlags.LoginExecFlags
+
+	account, err := buildIdpAccount(loginFlags)
+	if err != nil {
+		return errors.Wrap(err, "error building login details")
+	}
+
+	server, listener, stop, err := startCredentialServer(account, loginFlags, serveFlags.Address)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	if envVars, err := server.EnvVars(listener); err == nil {
+		for _, envVar := range envVars[:2] {
+			fmt.Println("export", envVar)
+		}
+	} else {
+		fmt.Println("Serving credentials on", listener.Addr().String())
+		fmt.Println("Authorization token:", server.Token)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+
+	return nil
+}
+
+// startCredentialServer log in and start serving the credentials of the role, the returned func stops the server
+func startCredentialServer(account *cfg.IDPAccount, loginFlags *flags.LoginExecFlags, address string) (*credserver.Server, net.Listener, func(), error) {
+
+	logger := logrus.WithField("command", "serve")
+
+	server, err := credserver.New(func() (*awsconfig.AWSCredentials, error) {
+		return loadRoleCredentials(os.Stdout, account, loginFlags)
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// log in up front so any prompts happen before clients start asking for credentials
+	_, err = server.Credentials()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	listener, err := credserver.Listen(address)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "error listening for credential requests")
+	}
+
+	httpServer := &http.Server{Handler: server}
+	done := make(chan struct{})
+
+	go server.RefreshLoop(done)
+	go func() {
+		err := httpServer.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			logger.WithError(err).Error("credential server stopped")
+		}
+	}()
+
+	stop := func() {
+		close(done)
+		err := httpServer.Shutdown(context.Background())
+		if err != nil {
+			logger.WithError(err).Debug("unable to stop credential server")
+		}
+	}
+
+	return server, listener, stop, nil
+}
+
+// execWithServer run the command with the AWS SDKs pointed at a credential server living as long as the command
+func execWithServer(account *cfg.IDPAccount, execFlags *flags.LoginExecFlags, cmdline []string) error {
+	server, listener, stop, err := startCredentialServer(account, execFlags, "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	envVars, err := server.EnvVars(listener)
+	if err != nil {
+		return err
+	}
+
+	return runCmd(execFlags, cmdline, envVars)
+}
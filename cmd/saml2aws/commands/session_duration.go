@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/pkg/errors"
+	"github.com/versent/saml2aws/pkg/cfg"
+)
+
+// assumeRoleWithSAMLFunc matches sts.AssumeRoleWithSAML so the duration negotiation can be tested
+type assumeRoleWithSAMLFunc func(*sts.AssumeRoleWithSAMLInput) (*sts.AssumeRoleWithSAMLOutput, error)
+
+// sessionDurations returns the session durations, in seconds, to request from STS in order of preference
+func sessionDurations(account *cfg.IDPAccount, idpSessionDuration int64) ([]int64, error) {
+	if !account.SessionDurationAuto {
+		return []int64{int64(account.SessionDuration)}, nil
+	}
+
+	ladder, err := parseSessionDurationLadder(account.SessionDurationLadder)
+	if err != nil {
+		return nil, err
+	}
+
+	return clampSessionDurations(ladder, idpSessionDuration), nil
+}
+
+// parseSessionDurationLadder parse a comma separated list of durations, either go durations such as 12h or seconds
+func parseSessionDurationLadder(ladder string) ([]int64, error) {
+	if ladder == "" {
+		ladder = cfg.DefaultSessionDurationLadder
+	}
+
+	durations := []int64{}
+
+	for _, step := range strings.Split(ladder, ",") {
+		step = strings.TrimSpace(step)
+		if step == "" {
+			continue
+		}
+
+		seconds, err := strconv.ParseInt(step, 10, 64)
+		if err != nil {
+			d, err := time.ParseDuration(step)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid session duration %s in ladder", step)
+			}
+			seconds = int64(d / time.Second)
+		}
+
+		if seconds <= 0 {
+			return nil, errors.Errorf("invalid session duration %s in ladder", step)
+		}
+
+		durations = append(durations, seconds)
+	}
+
+	if len(durations) == 0 {
+		return nil, errors.New("session duration ladder is empty")
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] > durations[j] })
+
+	return durations, nil
+}
+
+// clampSessionDurations drop the steps of the ladder which exceed the maximum supplied by the IdP, the IdP maximum
+// is tried first when it falls between two steps
+func clampSessionDurations(ladder []int64, idpSessionDuration int64) []int64 {
+	if idpSessionDuration <= 0 {
+		return ladder
+	}
+
+	durations := []int64{}
+
+	for _, step := range ladder {
+		if step > idpSessionDuration {
+			continue
+		}
+		if len(durations) == 0 && step != idpSessionDuration {
+			durations = append(durations, idpSessionDuration)
+		}
+		durations = append(durations, step)
+	}
+
+	if len(durations) == 0 {
+		durations = append(durations, idpSessionDuration)
+	}
+
+	return durations
+}
+
+// assumeRoleWithSessionDurations request credentials stepping down through the durations while STS reports the
+// duration exceeds the maximum session duration configured on the role
+func assumeRoleWithSessionDurations(out io.Writer, assumeRole assumeRoleWithSAMLFunc, params *sts.AssumeRoleWithSAMLInput, durations []int64) (*sts.AssumeRoleWithSAMLOutput, int64, error) {
+	for i, duration := range durations {
+		params.DurationSeconds = aws.Int64(duration)
+
+		resp, err := assumeRole(params)
+		if err == nil {
+			return resp, duration, nil
+		}
+
+		if !isMaxSessionDurationError(err) || i == len(durations)-1 {
+			return nil, 0, err
+		}
+
+		fmt.Fprintf(out, "Session duration of %s exceeds the maximum for this role, retrying with %s\n",
+			formatSessionDuration(duration), formatSessionDuration(durations[i+1]))
+	}
+
+	return nil, 0, errors.New("no session durations to request")
+}
+
+func isMaxSessionDurationError(err error) bool {
+	if awsErr, ok := err.(awserr.Error); ok {
+		return awsErr.Code() == "ValidationError" && strings.Contains(awsErr.Message(), "MaxSessionDuration")
+	}
+	return false
+}
+
+func formatSessionDuration(seconds int64) string {
+	return (time.Duration(seconds) * time.Second).String()
+}
@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/assert"
+	"github.com/versent/saml2aws/pkg/cfg"
+)
+
+func TestSessionDurationsFixed(t *testing.T) {
+	account := &cfg.IDPAccount{SessionDuration: 3600}
+
+	durations, err := sessionDurations(account, 28800)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{3600}, durations)
+}
+
+func TestSessionDurationsAuto(t *testing.T) {
+	account := &cfg.IDPAccount{SessionDuration: 3600, SessionDurationAuto: true}
+
+	durations, err := sessionDurations(account, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{43200, 28800, 14400, 3600}, durations)
+
+	durations, err = sessionDurations(account, 28800)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{28800, 14400, 3600}, durations)
+
+	durations, err = sessionDurations(account, 7200)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{7200, 3600}, durations)
+
+	durations, err = sessionDurations(account, 1800)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1800}, durations)
+}
+
+func TestParseSessionDurationLadder(t *testing.T) {
+	durations, err := parseSessionDurationLadder("1h, 7200,90m")
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{7200, 5400, 3600}, durations)
+
+	_, err = parseSessionDurationLadder("12h,forever")
+	assert.Error(t, err)
+
+	_, err = parseSessionDurationLadder(" , ")
+	assert.Error(t, err)
+}
+
+func TestAssumeRoleWithSessionDurationsStepsDown(t *testing.T) {
+	requested := []int64{}
+
+	assumeRole := func(params *sts.AssumeRoleWithSAMLInput) (*sts.AssumeRoleWithSAMLOutput, error) {
+		requested = append(requested, aws.Int64Value(params.DurationSeconds))
+		if aws.Int64Value(params.DurationSeconds) > 3600 {
+			return nil, awserr.New("ValidationError", "The requested DurationSeconds exceeds the MaxSessionDuration set for this role.", nil)
+		}
+		return &sts.AssumeRoleWithSAMLOutput{}, nil
+	}
+
+	resp, duration, err := assumeRoleWithSessionDurations(ioutil.Discard, assumeRole, &sts.AssumeRoleWithSAMLInput{}, []int64{43200, 14400, 3600})
+	assert.Nil(t, err)
+	assert.NotNil(t, resp)
+	assert.Equal(t, int64(3600), duration)
+	assert.Equal(t, []int64{43200, 14400, 3600}, requested)
+}
+
+func TestAssumeRoleWithSessionDurationsOtherError(t *testing.T) {
+	calls := 0
+
+	assumeRole := func(params *sts.AssumeRoleWithSAMLInput) (*sts.AssumeRoleWithSAMLOutput, error) {
+		calls++
+		return nil, awserr.New("AccessDenied", "Not authorized to perform sts:AssumeRoleWithSAML", nil)
+	}
+
+	_, _, err := assumeRoleWithSessionDurations(ioutil.Discard, assumeRole, &sts.AssumeRoleWithSAMLInput{}, []int64{43200, 3600})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
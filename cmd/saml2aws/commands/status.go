@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/versent/saml2aws/helper/credentials"
+	"github.com/versent/saml2aws/pkg/awsconfig"
+	"github.com/versent/saml2aws/pkg/cfg"
+	"github.com/versent/saml2aws/pkg/flags"
+)
+
+// profileStatus the state of the credentials saved for an idp account
+type profileStatus struct {
+	IdpAccount       string     `json:"idp_account"`
+	Profile          string     `json:"profile"`
+	PrincipalARN     string     `json:"principal_arn,omitempty"`
+	Expires          *time.Time `json:"expires,omitempty"`
+	ExpiresInSeconds int64      `json:"expires_in_seconds"`
+	Expired          bool       `json:"expired"`
+	PasswordStored   bool       `json:"password_stored"`
+	Verified         *bool      `json:"verified,omitempty"`
+	Error            string     `json:"error,omitempty"`
+}
+
+// Status list the idp accounts along with the expiry of the credentials saved for each, returning an error if any
+// have expired
+func Status(statusFlags *flags.StatusFlags) error {
+
+	logger := logrus.WithField("command", "status")
+
+	cfgm, err := cfg.NewConfigManager(cfg.DefaultConfigPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to load configuration")
+	}
+
+	names := statusFlags.IdpAccounts
+	if len(names) == 0 {
+		names, err = cfgm.ListIDPAccounts()
+		if err != nil {
+			return errors.Wrap(err, "failed to list idp accounts")
+		}
+	}
+
+	statuses := []*profileStatus{}
+	expired := []string{}
+
+	for _, name := range names {
+		account, err := cfgm.LoadIDPAccount(name)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load idp account %s", name)
+		}
+
+		status := buildProfileStatus(name, account, time.Now())
+
+		if statusFlags.Verify && !status.Expired {
+			ok, err := checkToken(account.Profile, status.PrincipalARN)
+			if err != nil {
+				logger.WithError(err).WithField("profile", account.Profile).Debug("error verifying token")
+				status.Error = err.Error()
+			}
+			status.Verified = &ok
+		}
+
+		if status.Expired || (status.Verified != nil && !*status.Verified) {
+			expired = append(expired, name)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	switch statusFlags.Format {
+	case "json":
+		err = printStatusJSON(os.Stdout, statuses)
+	default:
+		err = printStatusTable(os.Stdout, statuses)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(expired) > 0 {
+		return errors.Errorf("credentials have expired for: %s", strings.Join(expired, ", "))
+	}
+
+	return nil
+}
+
+func buildProfileStatus(name string, account *cfg.IDPAccount, now time.Time) *profileStatus {
+	status := &profileStatus{
+		IdpAccount: name,
+		Profile:    account.Profile,
+		Expired:    true,
+	}
+
+	if account.URL != "" {
+		stored, err := credentials.CredentialsStored(account.URL)
+		if err != nil {
+			status.Error = err.Error()
+		}
+		status.PasswordStored = stored
+	}
+
+	awsCreds, err := awsconfig.NewSharedCredentials(account.Profile).Load()
+	if err != nil {
+		return status
+	}
+
+	status.PrincipalARN = awsCreds.PrincipalARN
+	status.Expires = &awsCreds.Expires
+	status.ExpiresInSeconds = int64(awsCreds.Expires.Sub(now).Seconds())
+	status.Expired = !now.Before(awsCreds.Expires)
+
+	if status.ExpiresInSeconds < 0 {
+		status.ExpiresInSeconds = 0
+	}
+
+	return status
+}
+
+func printStatusJSON(w io.Writer, statuses []*profileStatus) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(statuses)
+}
+
+func printStatusTable(w io.Writer, statuses []*profileStatus) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "IDP ACCOUNT\tPROFILE\tPRINCIPAL\tEXPIRES IN\tPASSWORD\tVERIFIED")
+
+	for _, status := range statuses {
+		principal := status.PrincipalARN
+		if principal == "" {
+			principal = "-"
+		}
+
+		expiresIn := "expired"
+		if !status.Expired {
+			expiresIn = formatTimeLeft(time.Duration(status.ExpiresInSeconds) * time.Second)
+		}
+		if status.Expires == nil {
+			expiresIn = "no credentials"
+		}
+
+		password := "no"
+		if status.PasswordStored {
+			password = "stored"
+		}
+
+		verified := "-"
+		if status.Verified != nil {
+			verified = fmt.Sprintf("%t", *status.Verified)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", status.IdpAccount, status.Profile, principal, expiresIn, password, verified)
+	}
+
+	return tw.Flush()
+}
+
+// formatTimeLeft format the time left to the minute, for example 3h12m
+func formatTimeLeft(d time.Duration) string {
+	if d < time.Minute {
+		return "<1m"
+	}
+
+	return strings.TrimSuffix(d.Truncate(time.Minute).String(), "0s")
+}
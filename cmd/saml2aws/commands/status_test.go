@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/versent/saml2aws/helper/credentials"
+	"github.com/versent/saml2aws/pkg/awsconfig"
+	"github.com/versent/saml2aws/pkg/cfg"
+)
+
+type storedPasswordHelper struct {
+	credentials.Helper
+	url string
+}
+
+func (h *storedPasswordHelper) Get(serverURL string) (string, string, error) {
+	if serverURL != h.url {
+		return "", "", credentials.ErrCredentialsNotFound
+	}
+	return "wolfeidau", "password", nil
+}
+
+func TestBuildProfileStatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "status")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	previousFile := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	defer os.Setenv("AWS_SHARED_CREDENTIALS_FILE", previousFile)
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+
+	previousHelper := credentials.CurrentHelper
+	defer func() { credentials.CurrentHelper = previousHelper }()
+	credentials.CurrentHelper = &storedPasswordHelper{url: "https://id.example.com"}
+
+	now := time.Now()
+
+	err = awsconfig.NewSharedCredentials("saml").Save(&awsconfig.AWSCredentials{
+		AWSAccessKey: "AKIA",
+		PrincipalARN: "arn:aws:sts::000000000001:assumed-role/Development/wolfeidau",
+		Expires:      now.Add(90 * time.Minute),
+	})
+	require.Nil(t, err)
+
+	status := buildProfileStatus("default", &cfg.IDPAccount{URL: "https://id.example.com", Profile: "saml"}, now)
+	assert.Equal(t, "arn:aws:sts::000000000001:assumed-role/Development/wolfeidau", status.PrincipalARN)
+	assert.False(t, status.Expired)
+	assert.True(t, status.PasswordStored)
+	assert.InDelta(t, 90*60, status.ExpiresInSeconds, 1)
+
+	status = buildProfileStatus("default", &cfg.IDPAccount{URL: "https://id.example.com", Profile: "saml"}, now.Add(2*time.Hour))
+	assert.True(t, status.Expired)
+	assert.Equal(t, int64(0), status.ExpiresInSeconds)
+
+	status = buildProfileStatus("other", &cfg.IDPAccount{URL: "https://id.other.com", Profile: "other"}, now)
+	assert.True(t, status.Expired)
+	assert.False(t, status.PasswordStored)
+	assert.Nil(t, status.Expires)
+}
+
+func TestPrintStatusTable(t *testing.T) {
+	expires := time.Now()
+	verified := true
+
+	buf := new(bytes.Buffer)
+	err := printStatusTable(buf, []*profileStatus{
+		{IdpAccount: "default", Profile: "saml", PrincipalARN: "arn:aws:sts::000000000001:assumed-role/Development/wolfeidau", Expires: &expires, ExpiresInSeconds: 3720, PasswordStored: true, Verified: &verified},
+		{IdpAccount: "other", Profile: "other", Expired: true},
+	})
+	require.Nil(t, err)
+
+	assert.Contains(t, buf.String(), "default      saml     arn:aws:sts::000000000001:assumed-role/Development/wolfeidau  1h2m            stored    true")
+	assert.Contains(t, buf.String(), "other        other    -                                                             no credentials  no        -")
+}
+
+func TestFormatTimeLeft(t *testing.T) {
+	assert.Equal(t, "<1m", formatTimeLeft(30*time.Second))
+	assert.Equal(t, "45m", formatTimeLeft(45*time.Minute+10*time.Second))
+	assert.Equal(t, "3h12m", formatTimeLeft(3*time.Hour+12*time.Minute))
+}
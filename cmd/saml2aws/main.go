@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/alecthomas/kingpin"
 	"github.com/sirupsen/logrus"
 	"github.com/versent/saml2aws/cmd/saml2aws/commands"
 	"github.com/versent/saml2aws/pkg/flags"
+	"github.com/versent/saml2aws/pkg/shell"
 )
 
 var (
@@ -41,6 +44,37 @@ func buildCmdList(s kingpin.Settings) (target *[]string) {
 	return
 }
 
+// The `sessionDurationValue` type accepts either a number of seconds or `auto`
+// for the session duration flag
+type sessionDurationValue struct {
+	commonFlags *flags.CommonFlags
+}
+
+func (v *sessionDurationValue) Set(value string) error {
+	if value == "auto" {
+		v.commonFlags.SessionDurationAuto = true
+		v.commonFlags.SessionDuration = 0
+		return nil
+	}
+
+	duration, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("session duration must be a number of seconds or auto, got %s", value)
+	}
+
+	v.commonFlags.SessionDuration = duration
+	v.commonFlags.SessionDurationAuto = false
+
+	return nil
+}
+
+func (v *sessionDurationValue) String() string {
+	if v.commonFlags.SessionDurationAuto {
+		return "auto"
+	}
+	return strconv.Itoa(v.commonFlags.SessionDuration)
+}
+
 func main() {
 
 	app := kingpin.New("saml2aws", "A command line tool to help with SAML access to the AWS token service.")
@@ -60,11 +94,14 @@ func main() {
 	app.Flag("username", "The username used to login. (env: SAML2AWS_USERNAME)").Envar("SAML2AWS_USERNAME").StringVar(&commonFlags.Username)
 	app.Flag("password", "The password used to login. (env: SAML2AWS_PASSWORD)").Envar("SAML2AWS_PASSWORD").StringVar(&commonFlags.Password)
 	app.Flag("mfa-token", "The current MFA token (supported in Keycloak, ADFS). (env: SAML2AWS_MFA_TOKEN)").Envar("SAML2AWS_MFA_TOKEN").StringVar(&commonFlags.MFAToken)
-	app.Flag("role", "The ARN of the role to assume. (env: SAML2AWS_ROLE)").Envar("SAML2AWS_ROLE").StringVar(&commonFlags.RoleArn)
+	app.Flag("role", "The role to assume, as an ARN, glob, re:<regexp>, account/role or alias from the roles section. (env: SAML2AWS_ROLE)").Envar("SAML2AWS_ROLE").StringVar(&commonFlags.RoleArn)
 	app.Flag("aws-urn", "The URN used by SAML when you login. (env: SAML2AWS_AWS_URN)").Envar("SAML2AWS_AWS_URN").StringVar(&commonFlags.AmazonWebservicesURN)
 	app.Flag("skip-prompt", "Skip prompting for parameters during login.").BoolVar(&commonFlags.SkipPrompt)
-	app.Flag("session-duration", "The duration of your AWS Session. (env: SAML2AWS_SESSION_DURATION)").Envar("SAML2AWS_SESSION_DURATION").IntVar(&commonFlags.SessionDuration)
+	app.Flag("session-duration", "The duration of your AWS Session in seconds, or auto to negotiate it. (env: SAML2AWS_SESSION_DURATION)").Envar("SAML2AWS_SESSION_DURATION").SetValue(&sessionDurationValue{commonFlags})
+	app.Flag("session-duration-ladder", "The session durations tried in turn when the session duration is auto. (env: SAML2AWS_SESSION_DURATION_LADDER)").Envar("SAML2AWS_SESSION_DURATION_LADDER").StringVar(&commonFlags.SessionDurationLadder)
 	app.Flag("disable-keychain", "Do not use keychain at all.").Envar("SAML2AWS_DISABLE_KEYCHAIN").BoolVar(&commonFlags.DisableKeychain)
+	app.Flag("no-assertion-cache", "Do not reuse or cache the SAML assertion. (env: SAML2AWS_NO_ASSERTION_CACHE)").Envar("SAML2AWS_NO_ASSERTION_CACHE").BoolVar(&commonFlags.DisableAssertionCache)
+	app.Flag("no-credential-cache", "Do not reuse or cache the credentials of credential-process and serve in the keychain. (env: SAML2AWS_NO_CREDENTIAL_CACHE)").Envar("SAML2AWS_NO_CREDENTIAL_CACHE").BoolVar(&commonFlags.DisableCredentialCache)
 
 	// `configure` command and settings
 	cmdConfigure := app.Command("configure", "Configure a new IDP account.")
@@ -84,6 +121,8 @@ func main() {
 	cmdLogin.Flag("profile", "The AWS profile to save the temporary credentials. (env: SAML2AWS_PROFILE)").Short('p').Envar("SAML2AWS_PROFILE").StringVar(&commonFlags.Profile)
 	cmdLogin.Flag("duo-mfa-option", "The MFA option you want to use to authenticate with").Envar("SAML2AWS_DUO_MFA_OPTION").EnumVar(&loginFlags.DuoMFAOption, "Passcode", "Duo Push")
 	cmdLogin.Flag("force", "Refresh credentials even if not expired.").BoolVar(&loginFlags.Force)
+	cmdLogin.Flag("roles", "Login to every role matching these patterns or aliases, saving each to its own profile.").StringsVar(&loginFlags.Roles)
+	cmdLogin.Flag("all", "Login to every configured IDP account concurrently.").BoolVar(&loginFlags.All)
 
 	// `exec` command and settings
 	cmdExec := app.Command("exec", "Exec the supplied command with env vars from STS token.")
@@ -91,6 +130,8 @@ func main() {
 	execFlags.CommonFlags = commonFlags
 	cmdExec.Flag("profile", "The AWS profile to save the temporary credentials. (env: SAML2AWS_PROFILE)").Envar("SAML2AWS_PROFILE").Short('p').StringVar(&commonFlags.Profile)
 	cmdExec.Flag("exec-profile", "The AWS profile to utilize for command execution. Useful to allow the aws cli to perform secondary role assumption. (env: SAML2AWS_EXEC_PROFILE)").Envar("SAML2AWS_EXEC_PROFILE").StringVar(&execFlags.ExecProfile)
+	cmdExec.Flag("with-server", "Serve refreshed credentials to the command over the ECS container credentials protocol.").BoolVar(&execFlags.WithServer)
+	cmdExec.Flag("direct", "Run the command without a shell, the default when the command follows --.").BoolVar(&execFlags.Direct)
 	cmdLine := buildCmdList(cmdExec.Arg("command", "The command to execute."))
 
 	// `list` command and settings
@@ -103,11 +144,59 @@ func main() {
 	scriptFlags := new(flags.LoginExecFlags)
 	scriptFlags.CommonFlags = commonFlags
 	cmdScript.Flag("profile", "The AWS profile to save the temporary credentials. (env: SAML2AWS_PROFILE)").Envar("SAML2AWS_PROFILE").Short('p').StringVar(&commonFlags.Profile)
-	var shell string
+	var scriptShell string
+	var scriptUnset bool
 	cmdScript.
-		Flag("shell", "Type of shell environment. Options include: bash, powershell, fish").
+		Flag("shell", "Type of shell environment or file format. Options include: "+strings.Join(commands.ScriptFormats(), ", ")).
 		Default("bash").
-		EnumVar(&shell, "bash", "powershell", "fish")
+		EnumVar(&scriptShell, commands.ScriptFormats()...)
+	cmdScript.Flag("unset", "Emit the commands that clear the environment variables again.").BoolVar(&scriptUnset)
+
+	// `assertion` command and settings
+	cmdAssertion := app.Command("assertion", "Decode and display the contents of a SAML assertion.")
+	assertionFlags := new(flags.LoginExecFlags)
+	assertionFlags.CommonFlags = commonFlags
+	var assertionFile, assertionFormat string
+	cmdAssertion.Flag("file", "Read the SAML response from a file rather than logging in, use - for stdin.").StringVar(&assertionFile)
+	cmdAssertion.
+		Flag("format", "Output format. Options include: text, json").
+		Default("text").
+		EnumVar(&assertionFormat, "text", "json")
+
+	// `console` command and settings
+	cmdConsole := app.Command("console", "Open the AWS console signed in with the STS token.")
+	consoleFlags := &flags.ConsoleFlags{LoginExecFlags: &flags.LoginExecFlags{CommonFlags: commonFlags}}
+	cmdConsole.Flag("profile", "The AWS profile holding the temporary credentials. (env: SAML2AWS_PROFILE)").Envar("SAML2AWS_PROFILE").Short('p').StringVar(&commonFlags.Profile)
+	cmdConsole.Flag("destination", "The console page to open, either a service path such as ec2/home or a full URL.").StringVar(&consoleFlags.Destination)
+	cmdConsole.Flag("issuer", "The URL the console returns to when the session ends.").StringVar(&consoleFlags.Issuer)
+	cmdConsole.Flag("federation-url", "Override the AWS federation endpoint. (env: SAML2AWS_FEDERATION_URL)").Envar("SAML2AWS_FEDERATION_URL").StringVar(&consoleFlags.FederationURL)
+	cmdConsole.Flag("link", "Print the console login URL instead of opening a browser.").BoolVar(&consoleFlags.Link)
+
+	// `status` command and settings
+	cmdStatus := app.Command("status", "Show the credentials saved for each IDP account and when they expire.")
+	statusFlags := &flags.StatusFlags{CommonFlags: commonFlags}
+	cmdStatus.Arg("idp-accounts", "The IDP accounts to show, defaults to all of them.").StringsVar(&statusFlags.IdpAccounts)
+	cmdStatus.Flag("verify", "Check the credentials are still accepted by calling STS GetCallerIdentity.").BoolVar(&statusFlags.Verify)
+	cmdStatus.
+		Flag("format", "Output format. Options include: table, json").
+		Default("table").
+		EnumVar(&statusFlags.Format, "table", "json")
+
+	// `logout` command and settings
+	cmdLogout := app.Command("logout", "Remove the STS token, saved password and cached SAML assertion.")
+	logoutFlags := &flags.LogoutFlags{LoginExecFlags: &flags.LoginExecFlags{CommonFlags: commonFlags}}
+	cmdLogout.Flag("profile", "The AWS profile holding the temporary credentials. (env: SAML2AWS_PROFILE)").Envar("SAML2AWS_PROFILE").Short('p').StringVar(&commonFlags.Profile)
+	cmdLogout.Flag("slo", "Also end the IdP session with a SAML single logout request.").BoolVar(&logoutFlags.SLO)
+
+	// `credential-process` command and settings
+	cmdCredentialProcess := app.Command("credential-process", "Print the STS token in the format of an AWS credential_process, logging in when needed.")
+	credentialProcessFlags := new(flags.LoginExecFlags)
+	credentialProcessFlags.CommonFlags = commonFlags
+
+	// `serve` command and settings
+	cmdServe := app.Command("serve", "Serve the STS token over the ECS container credentials protocol, refreshing it before it expires.")
+	serveFlags := &flags.ServeFlags{LoginExecFlags: &flags.LoginExecFlags{CommonFlags: commonFlags}}
+	cmdServe.Flag("address", "The loopback address to listen on, or unix:<path> for a unix socket. (env: SAML2AWS_SERVE_ADDRESS)").Envar("SAML2AWS_SERVE_ADDRESS").Default("127.0.0.1:0").StringVar(&serveFlags.Address)
 
 	// Trigger the parsing of the command line inputs via kingpin
 	command := kingpin.MustParse(app.Parse(os.Args[1:]))
@@ -124,8 +213,11 @@ func main() {
 		errtpl = "%+v\n"
 	}
 
-	// Set the default transport settings so all http clients will pick them up.
-	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: commonFlags.SkipVerify}
+	// Set the default transport settings so the http clients outside of the providers pick them up, the providers
+	// build their own transports from the TLS settings of the idp account.
+	if commonFlags.SkipVerify {
+		http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
 	http.DefaultTransport.(*http.Transport).Proxy = http.ProxyFromEnvironment
 
 	logrus.WithField("command", command).Debug("Running")
@@ -133,19 +225,50 @@ func main() {
 	var err error
 	switch command {
 	case cmdScript.FullCommand():
-		err = commands.Script(scriptFlags, shell)
+		err = commands.Script(scriptFlags, scriptShell, scriptUnset)
 	case cmdLogin.FullCommand():
 		err = commands.Login(loginFlags)
 	case cmdExec.FullCommand():
+		if commandAfterSeparator(os.Args, *cmdLine) {
+			execFlags.Direct = true
+		}
 		err = commands.Exec(execFlags, *cmdLine)
 	case cmdListRoles.FullCommand():
 		err = commands.ListRoles(listRolesFlags)
+	case cmdAssertion.FullCommand():
+		err = commands.Assertion(assertionFlags, assertionFile, assertionFormat)
+	case cmdConsole.FullCommand():
+		err = commands.Console(consoleFlags)
+	case cmdStatus.FullCommand():
+		err = commands.Status(statusFlags)
+	case cmdLogout.FullCommand():
+		err = commands.Logout(logoutFlags)
+	case cmdCredentialProcess.FullCommand():
+		err = commands.CredentialProcess(credentialProcessFlags)
+	case cmdServe.FullCommand():
+		err = commands.Serve(serveFlags)
 	case cmdConfigure.FullCommand():
 		err = commands.Configure(configFlags)
 	}
 
+	// the child of exec has already reported its failure, only its exit code is passed on
+	if code, ok := shell.ExitCode(err); ok {
+		os.Exit(code)
+	}
+
 	if err != nil {
-		fmt.Printf(errtpl, err)
+		fmt.Fprintf(os.Stderr, errtpl, err)
 		os.Exit(1)
 	}
 }
+
+// commandAfterSeparator returns true if the command line was given after --, which kingpin drops while parsing
+func commandAfterSeparator(args []string, cmdline []string) bool {
+	for i, arg := range args {
+		if arg == "--" {
+			return len(args)-i-1 == len(cmdline)
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,334 @@
+package saml2aws
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+
+	// register the digests used by RSA-OAEP key transport
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+
+	"github.com/beevik/etree"
+	"github.com/pkg/errors"
+	"github.com/versent/saml2aws/pkg/cfg"
+)
+
+const (
+	encryptedAssertionTag = "EncryptedAssertion"
+	encryptedDataTag      = "EncryptedData"
+	encryptedKeyTag       = "EncryptedKey"
+	encryptionMethodTag   = "EncryptionMethod"
+	cipherValuePath       = "CipherData/CipherValue"
+
+	xmlencNamespace   = "http://www.w3.org/2001/04/xmlenc#"
+	xmlenc11Namespace = "http://www.w3.org/2009/xmlenc11#"
+
+	// key transport algorithms
+	algorithmRSA15        = xmlencNamespace + "rsa-1_5"
+	algorithmRSAOAEP      = xmlencNamespace + "rsa-oaep-mgf1p"
+	algorithmRSAOAEP11    = xmlenc11Namespace + "rsa-oaep"
+	algorithmMGF1SHA1     = xmlenc11Namespace + "mgf1sha1"
+	algorithmMGF1SHA256   = xmlenc11Namespace + "mgf1sha256"
+	algorithmMGF1SHA384   = xmlenc11Namespace + "mgf1sha384"
+	algorithmMGF1SHA512   = xmlenc11Namespace + "mgf1sha512"
+	algorithmDigestSHA1   = "http://www.w3.org/2000/09/xmldsig#sha1"
+	algorithmDigestSHA256 = xmlencNamespace + "sha256"
+	algorithmDigestSHA384 = "http://www.w3.org/2001/04/xmldsig-more#sha384"
+	algorithmDigestSHA512 = xmlencNamespace + "sha512"
+
+	// content encryption algorithms
+	algorithmTripleDESCBC = xmlencNamespace + "tripledes-cbc"
+	algorithmAES128CBC    = xmlencNamespace + "aes128-cbc"
+	algorithmAES192CBC    = xmlencNamespace + "aes192-cbc"
+	algorithmAES256CBC    = xmlencNamespace + "aes256-cbc"
+	algorithmAES128GCM    = xmlenc11Namespace + "aes128-gcm"
+	algorithmAES192GCM    = xmlenc11Namespace + "aes192-gcm"
+	algorithmAES256GCM    = xmlenc11Namespace + "aes256-gcm"
+)
+
+var (
+	// ErrEncryptedAssertion returned when the SAML response contains an encrypted assertion but no
+	// SP private key has been configured to decrypt it
+	ErrEncryptedAssertion = errors.New("saml assertion is encrypted, configure sp_private_key to decrypt it")
+
+	digestAlgorithms = map[string]crypto.Hash{
+		algorithmDigestSHA1:   crypto.SHA1,
+		algorithmDigestSHA256: crypto.SHA256,
+		algorithmDigestSHA384: crypto.SHA384,
+		algorithmDigestSHA512: crypto.SHA512,
+	}
+
+	mgfAlgorithms = map[string]crypto.Hash{
+		algorithmMGF1SHA1:   crypto.SHA1,
+		algorithmMGF1SHA256: crypto.SHA256,
+		algorithmMGF1SHA384: crypto.SHA384,
+		algorithmMGF1SHA512: crypto.SHA512,
+	}
+)
+
+// DecryptionEnabled returns true if the account has an SP private key configured
+func DecryptionEnabled(idpAccount *cfg.IDPAccount) bool {
+	return idpAccount.SPPrivateKey != ""
+}
+
+// LoadSPPrivateKey load the RSA private key used to decrypt assertions from the PEM file configured on the account
+func LoadSPPrivateKey(idpAccount *cfg.IDPAccount) (*rsa.PrivateKey, error) {
+	data, err := readConfigFile(idpAccount.SPPrivateKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read sp private key")
+	}
+
+	key, err := ParsePEMPrivateKey(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse sp private key %s", idpAccount.SPPrivateKey)
+	}
+
+	return key, nil
+}
+
+// ParsePEMPrivateKey parse a PKCS#1 or PKCS#8 PEM encoded RSA private key
+func ParsePEMPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+
+			rsaKey, ok := key.(*rsa.PrivateKey)
+			if !ok {
+				return nil, errors.New("private key is not an RSA key")
+			}
+
+			return rsaKey, nil
+		}
+	}
+
+	return nil, errors.New("no PEM encoded private key found")
+}
+
+// DecryptAssertion replace any EncryptedAssertion in the SAML response with the decrypted assertion, responses
+// without an encrypted assertion are returned unchanged
+func DecryptAssertion(data []byte, key *rsa.PrivateKey) ([]byte, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return nil, err
+	}
+
+	if doc.FindElement("//"+encryptedAssertionTag) == nil {
+		return data, nil
+	}
+
+	if err := decryptAssertions(doc.Root(), key); err != nil {
+		return nil, err
+	}
+
+	return doc.WriteToBytes()
+}
+
+// decryptAssertions replace each EncryptedAssertion below the element, in place, with the decrypted assertion
+func decryptAssertions(el *etree.Element, key *rsa.PrivateKey) error {
+	for _, encryptedAssertion := range el.FindElements(".//" + encryptedAssertionTag) {
+		if key == nil {
+			return ErrEncryptedAssertion
+		}
+
+		assertion, err := decryptElement(encryptedAssertion, key)
+		if err != nil {
+			return errors.Wrap(err, "failed to decrypt saml assertion")
+		}
+
+		parent := encryptedAssertion.Parent()
+		parent.InsertChild(encryptedAssertion, assertion)
+		parent.RemoveChild(encryptedAssertion)
+	}
+
+	return nil
+}
+
+func decryptElement(encryptedAssertion *etree.Element, key *rsa.PrivateKey) (*etree.Element, error) {
+	encryptedData := encryptedAssertion.FindElement("./" + encryptedDataTag)
+	if encryptedData == nil {
+		return nil, ErrMissingElement{Tag: encryptedDataTag}
+	}
+
+	// the key is usually carried in the KeyInfo of the encrypted data, some IdPs place it alongside instead
+	encryptedKey := encryptedData.FindElement(".//" + encryptedKeyTag)
+	if encryptedKey == nil {
+		encryptedKey = encryptedAssertion.FindElement("./" + encryptedKeyTag)
+	}
+	if encryptedKey == nil {
+		return nil, ErrMissingElement{Tag: encryptedKeyTag}
+	}
+
+	sessionKey, err := decryptKey(encryptedKey, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt session key")
+	}
+
+	plaintext, err := decryptData(encryptedData, sessionKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt assertion")
+	}
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(plaintext); err != nil {
+		return nil, errors.Wrap(err, "failed to parse decrypted assertion")
+	}
+
+	assertion := doc.Root()
+	if assertion == nil || assertion.Tag != assertionTag {
+		return nil, ErrMissingAssertion
+	}
+
+	return assertion, nil
+}
+
+func decryptKey(encryptedKey *etree.Element, key *rsa.PrivateKey) ([]byte, error) {
+	method := encryptedKey.FindElement("./" + encryptionMethodTag)
+	if method == nil {
+		return nil, ErrMissingElement{Tag: encryptionMethodTag}
+	}
+
+	ciphertext, err := cipherValue(encryptedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm := method.SelectAttrValue("Algorithm", "")
+
+	switch algorithm {
+	case algorithmRSA15:
+		return rsa.DecryptPKCS1v15(nil, key, ciphertext)
+	case algorithmRSAOAEP, algorithmRSAOAEP11:
+		hash := crypto.SHA1
+		if digestMethod := method.FindElement("./DigestMethod"); digestMethod != nil {
+			var ok bool
+			hash, ok = digestAlgorithms[digestMethod.SelectAttrValue("Algorithm", "")]
+			if !ok {
+				return nil, errors.Errorf("unsupported oaep digest %s", digestMethod.SelectAttrValue("Algorithm", ""))
+			}
+		}
+
+		// rsa-oaep-mgf1p always uses mgf1 with sha1, xmlenc 1.1 allows it to be chosen
+		mgfHash := crypto.SHA1
+		if mgf := method.FindElement("./MGF"); mgf != nil {
+			var ok bool
+			mgfHash, ok = mgfAlgorithms[mgf.SelectAttrValue("Algorithm", "")]
+			if !ok {
+				return nil, errors.Errorf("unsupported oaep mask generation function %s", mgf.SelectAttrValue("Algorithm", ""))
+			}
+		}
+
+		var label []byte
+		if params := method.FindElement("./OAEPparams"); params != nil {
+			label, err = base64.StdEncoding.DecodeString(stripWhitespace(params.Text()))
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to decode oaep params")
+			}
+		}
+
+		return key.Decrypt(nil, ciphertext, &rsa.OAEPOptions{Hash: hash, MGFHash: mgfHash, Label: label})
+	}
+
+	return nil, errors.Errorf("unsupported key transport algorithm %s", algorithm)
+}
+
+func decryptData(encryptedData *etree.Element, sessionKey []byte) ([]byte, error) {
+	method := encryptedData.FindElement("./" + encryptionMethodTag)
+	if method == nil {
+		return nil, ErrMissingElement{Tag: encryptionMethodTag}
+	}
+
+	ciphertext, err := cipherValue(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm := method.SelectAttrValue("Algorithm", "")
+
+	switch algorithm {
+	case algorithmAES128CBC, algorithmAES192CBC, algorithmAES256CBC:
+		block, err := aes.NewCipher(sessionKey)
+		if err != nil {
+			return nil, err
+		}
+		return decryptCBC(block, ciphertext)
+	case algorithmTripleDESCBC:
+		block, err := des.NewTripleDESCipher(sessionKey)
+		if err != nil {
+			return nil, err
+		}
+		return decryptCBC(block, ciphertext)
+	case algorithmAES128GCM, algorithmAES192GCM, algorithmAES256GCM:
+		block, err := aes.NewCipher(sessionKey)
+		if err != nil {
+			return nil, err
+		}
+		return decryptGCM(block, ciphertext)
+	}
+
+	return nil, errors.Errorf("unsupported content encryption algorithm %s", algorithm)
+}
+
+// decryptCBC the IV is prepended to the ciphertext and the padding is per XML Encryption, only the final byte which
+// holds the padding length is significant
+func decryptCBC(block cipher.Block, ciphertext []byte) ([]byte, error) {
+	blockSize := block.BlockSize()
+	if len(ciphertext) < 2*blockSize || len(ciphertext)%blockSize != 0 {
+		return nil, errors.New("ciphertext is not a multiple of the block size")
+	}
+
+	iv, ciphertext := ciphertext[:blockSize], ciphertext[blockSize:]
+
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > blockSize {
+		return nil, errors.New("invalid padding")
+	}
+
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+// decryptGCM the 96 bit IV is prepended to the ciphertext and the 128 bit tag appended
+func decryptGCM(block cipher.Block, ciphertext []byte) ([]byte, error) {
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func cipherValue(el *etree.Element) ([]byte, error) {
+	value := el.FindElement("./" + cipherValuePath)
+	if value == nil {
+		return nil, ErrMissingElement{Tag: "CipherValue"}
+	}
+
+	return base64.StdEncoding.DecodeString(stripWhitespace(value.Text()))
+}
@@ -0,0 +1,265 @@
+package saml2aws
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/stretchr/testify/require"
+)
+
+const testEncryptedAssertion = `<saml2:EncryptedAssertion xmlns:saml2="urn:oasis:names:tc:SAML:2.0:assertion">
+  <xenc:EncryptedData xmlns:xenc="http://www.w3.org/2001/04/xmlenc#" Type="http://www.w3.org/2001/04/xmlenc#Element">
+    <xenc:EncryptionMethod Algorithm="%s"/>
+    <ds:KeyInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#">
+      <xenc:EncryptedKey>
+        <xenc:EncryptionMethod Algorithm="%s">%s</xenc:EncryptionMethod>
+        <xenc:CipherData><xenc:CipherValue>%s</xenc:CipherValue></xenc:CipherData>
+      </xenc:EncryptedKey>
+    </ds:KeyInfo>
+    <xenc:CipherData><xenc:CipherValue>%s</xenc:CipherValue></xenc:CipherData>
+  </xenc:EncryptedData>
+</saml2:EncryptedAssertion>`
+
+type testEncryption struct {
+	keyAlgorithm  string
+	keyParams     string
+	hash          crypto.Hash
+	dataAlgorithm string
+}
+
+// encryptTestResponse replace the assertion in the SAML response with an EncryptedAssertion
+func encryptTestResponse(t *testing.T, data []byte, pub *rsa.PublicKey, enc testEncryption) []byte {
+	doc := etree.NewDocument()
+	require.Nil(t, doc.ReadFromBytes(data))
+
+	response := doc.Root()
+	if response.SelectAttr("xmlns:saml2") == nil {
+		// the fixture uses the saml2 prefix without declaring it
+		response.CreateAttr("xmlns:saml2", "urn:oasis:names:tc:SAML:2.0:assertion")
+	}
+
+	assertion := response.FindElement("./Assertion")
+
+	detached, err := detachElement(assertion)
+	require.Nil(t, err)
+
+	assertionDoc := etree.NewDocument()
+	assertionDoc.SetRoot(detached)
+	plaintext, err := assertionDoc.WriteToBytes()
+	require.Nil(t, err)
+
+	var sessionKey, ciphertext []byte
+
+	switch enc.dataAlgorithm {
+	case algorithmAES128CBC, algorithmAES192CBC, algorithmAES256CBC:
+		sessionKey = randomBytes(t, map[string]int{algorithmAES128CBC: 16, algorithmAES192CBC: 24, algorithmAES256CBC: 32}[enc.dataAlgorithm])
+		block, err := aes.NewCipher(sessionKey)
+		require.Nil(t, err)
+		ciphertext = encryptCBC(t, block, plaintext)
+	case algorithmTripleDESCBC:
+		sessionKey = randomBytes(t, 24)
+		block, err := des.NewTripleDESCipher(sessionKey)
+		require.Nil(t, err)
+		ciphertext = encryptCBC(t, block, plaintext)
+	case algorithmAES128GCM, algorithmAES256GCM:
+		sessionKey = randomBytes(t, map[string]int{algorithmAES128GCM: 16, algorithmAES256GCM: 32}[enc.dataAlgorithm])
+		block, err := aes.NewCipher(sessionKey)
+		require.Nil(t, err)
+		gcm, err := cipher.NewGCM(block)
+		require.Nil(t, err)
+		nonce := randomBytes(t, gcm.NonceSize())
+		ciphertext = gcm.Seal(nonce, nonce, plaintext, nil)
+	}
+
+	var encryptedKey []byte
+	if enc.keyAlgorithm == algorithmRSA15 {
+		encryptedKey, err = rsa.EncryptPKCS1v15(rand.Reader, pub, sessionKey)
+	} else {
+		encryptedKey, err = rsa.EncryptOAEP(enc.hash.New(), rand.Reader, pub, sessionKey, nil)
+	}
+	require.Nil(t, err)
+
+	encrypted := etree.NewDocument()
+	require.Nil(t, encrypted.ReadFromString(fmt.Sprintf(testEncryptedAssertion, enc.dataAlgorithm, enc.keyAlgorithm, enc.keyParams,
+		base64.StdEncoding.EncodeToString(encryptedKey), base64.StdEncoding.EncodeToString(ciphertext))))
+
+	response.InsertChild(assertion, encrypted.Root())
+	response.RemoveChild(assertion)
+
+	data, err = doc.WriteToBytes()
+	require.Nil(t, err)
+
+	return data
+}
+
+func encryptCBC(t *testing.T, block cipher.Block, plaintext []byte) []byte {
+	padding := block.BlockSize() - len(plaintext)%block.BlockSize()
+	plaintext = append(plaintext, bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	iv := randomBytes(t, block.BlockSize())
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+
+	return append(iv, ciphertext...)
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	require.Nil(t, err)
+	return b
+}
+
+var defaultTestEncryption = testEncryption{keyAlgorithm: algorithmRSAOAEP, hash: crypto.SHA1, dataAlgorithm: algorithmAES128CBC}
+
+func TestDecryptAssertion(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+
+	data, err := ioutil.ReadFile("testdata/assertion.xml")
+	require.Nil(t, err)
+
+	tests := []testEncryption{
+		defaultTestEncryption,
+		{keyAlgorithm: algorithmRSA15, dataAlgorithm: algorithmAES256CBC},
+		{keyAlgorithm: algorithmRSA15, dataAlgorithm: algorithmTripleDESCBC},
+		{keyAlgorithm: algorithmRSAOAEP, hash: crypto.SHA1, dataAlgorithm: algorithmAES128GCM},
+		{
+			keyAlgorithm:  algorithmRSAOAEP11,
+			keyParams:     `<ds:DigestMethod xmlns:ds="http://www.w3.org/2000/09/xmldsig#" Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"/><xenc11:MGF xmlns:xenc11="http://www.w3.org/2009/xmlenc11#" Algorithm="http://www.w3.org/2009/xmlenc11#mgf1sha256"/>`,
+			hash:          crypto.SHA256,
+			dataAlgorithm: algorithmAES256GCM,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.keyAlgorithm+" "+tt.dataAlgorithm, func(t *testing.T) {
+			encrypted := encryptTestResponse(t, data, &key.PublicKey, tt)
+
+			_, err := ParseSAMLAssertion(encrypted)
+			require.Equal(t, ErrEncryptedAssertion, err)
+
+			decrypted, err := DecryptAssertion(encrypted, key)
+			require.Nil(t, err)
+
+			assertion, err := ParseSAMLAssertion(decrypted)
+			require.Nil(t, err)
+			require.Equal(t, "_f85be5f5-584c-4711-8c9d-5b13c4c49f89", assertion.ID)
+			require.Len(t, assertion.Roles, 2)
+			require.Equal(t, int64(28800), assertion.SessionDuration)
+		})
+	}
+}
+
+func TestDecryptAssertionWrongKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+
+	data, err := ioutil.ReadFile("testdata/assertion.xml")
+	require.Nil(t, err)
+
+	encrypted := encryptTestResponse(t, data, &key.PublicKey, defaultTestEncryption)
+
+	_, err = DecryptAssertion(encrypted, otherKey)
+	require.Error(t, err)
+}
+
+func TestDecryptAssertionUnencrypted(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+
+	data, err := ioutil.ReadFile("testdata/assertion.xml")
+	require.Nil(t, err)
+
+	decrypted, err := DecryptAssertion(data, key)
+	require.Nil(t, err)
+	require.Equal(t, data, decrypted)
+}
+
+func TestVerifyEncryptedAssertionSignature(t *testing.T) {
+	ks, cert := newTestKeyStore(t)
+
+	signed := signTestResponse(t, ks, false)
+	encrypted := encryptTestResponse(t, signed, &ks.key.PublicKey, defaultTestEncryption)
+
+	verified, err := VerifyAssertionSignature(encrypted, []*x509.Certificate{cert}, ks.key)
+	require.Nil(t, err)
+
+	roles, err := ExtractAwsRoles(verified)
+	require.Nil(t, err)
+	require.Len(t, roles, 2)
+
+	_, err = VerifyAssertionSignature(encrypted, []*x509.Certificate{cert}, nil)
+	require.Equal(t, ErrEncryptedAssertion, err)
+}
+
+func TestVerifySignedResponseEncryptedAssertion(t *testing.T) {
+	ks, cert := newTestKeyStore(t)
+
+	data, err := ioutil.ReadFile("testdata/assertion.xml")
+	require.Nil(t, err)
+
+	doc := etree.NewDocument()
+	require.Nil(t, doc.ReadFromBytes(data))
+	assertion := doc.FindElement("//Assertion")
+	assertion.RemoveChild(assertion.FindElement("./Signature"))
+	data, err = doc.WriteToBytes()
+	require.Nil(t, err)
+
+	encrypted := encryptTestResponse(t, data, &ks.key.PublicKey, defaultTestEncryption)
+
+	// the response signature covers the encrypted assertion
+	doc = etree.NewDocument()
+	require.Nil(t, doc.ReadFromBytes(encrypted))
+
+	ctx := dsig.NewDefaultSigningContext(ks)
+	ctx.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	signEnvelopedAfterIssuer(t, ctx, doc.Root())
+
+	encrypted, err = doc.WriteToBytes()
+	require.Nil(t, err)
+
+	verified, err := VerifyAssertionSignature(encrypted, []*x509.Certificate{cert}, ks.key)
+	require.Nil(t, err)
+
+	roles, err := ExtractAwsRoles(verified)
+	require.Nil(t, err)
+	require.Len(t, roles, 2)
+}
+
+func TestParsePEMPrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	parsed, err := ParsePEMPrivateKey(pkcs1)
+	require.Nil(t, err)
+	require.Equal(t, key.N, parsed.N)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.Nil(t, err)
+	pkcs8 := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	parsed, err = ParsePEMPrivateKey(pkcs8)
+	require.Nil(t, err)
+	require.Equal(t, key.N, parsed.N)
+
+	_, err = ParsePEMPrivateKey([]byte("not a key"))
+	require.Error(t, err)
+}
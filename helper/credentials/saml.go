@@ -41,6 +41,38 @@ func SaveCredentials(url, username, password string) error {
 	return CurrentHelper.Add(creds)
 }
 
+// DeleteCredentials remove the user credentials, along with the OAuth client used by OneLogin.
+func DeleteCredentials(url, provider string) error {
+
+	urls := []string{url}
+	if provider == "OneLogin" {
+		urls = append(urls, path.Join(url, "/auth/oauth2/v2/token"))
+	}
+
+	for _, serverURL := range urls {
+		err := CurrentHelper.Delete(serverURL)
+		if err != nil && !IsErrCredentialsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CredentialsStored returns true if a password has been saved for the url
+func CredentialsStored(url string) (bool, error) {
+
+	_, password, err := CurrentHelper.Get(url)
+	if err != nil {
+		if IsErrCredentialsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return password != "", nil
+}
+
 // SupportsStorage will return true or false if storage is supported.
 func SupportsStorage() bool {
 	return CurrentHelper.SupportsCredentialStorage()
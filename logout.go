@@ -0,0 +1,134 @@
+package saml2aws
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/pkg/errors"
+	"github.com/versent/saml2aws/pkg/cfg"
+)
+
+const (
+	redirectBinding = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+
+	signatureAlgorithmRSASHA256 = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+)
+
+// ErrNoSLOEndpoint returned when neither the idp account nor the IdP metadata name a single logout endpoint
+var ErrNoSLOEndpoint = errors.New("no single logout endpoint configured, set slo_url or idp_metadata_file")
+
+// LocateSLOEndpoint return the single logout endpoint of the IdP, either configured on the account or the
+// HTTP-Redirect SingleLogoutService listed in the IdP metadata
+func LocateSLOEndpoint(idpAccount *cfg.IDPAccount) (string, error) {
+	if idpAccount.SLOURL != "" {
+		return idpAccount.SLOURL, nil
+	}
+
+	if idpAccount.IDPMetadataFile == "" {
+		return "", ErrNoSLOEndpoint
+	}
+
+	data, err := readConfigFile(idpAccount.IDPMetadataFile)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read idp metadata")
+	}
+
+	return ExtractMetadataSLOEndpoint(data)
+}
+
+// ExtractMetadataSLOEndpoint extract the HTTP-Redirect single logout endpoint from an IdP SAML metadata document
+func ExtractMetadataSLOEndpoint(data []byte) (string, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return "", err
+	}
+
+	for _, service := range doc.FindElements("//IDPSSODescriptor/SingleLogoutService") {
+		if service.SelectAttrValue("Binding", "") == redirectBinding {
+			return service.SelectAttrValue("Location", ""), nil
+		}
+	}
+
+	return "", ErrNoSLOEndpoint
+}
+
+// BuildLogoutRequest build a SAML LogoutRequest ending the IdP session the assertion was issued in
+func BuildLogoutRequest(issuer, destination string, subject SAMLSubject, sessionIndex string, now time.Time) ([]byte, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, errors.Wrap(err, "failed to generate request id")
+	}
+
+	doc := etree.NewDocument()
+
+	request := doc.CreateElement("samlp:LogoutRequest")
+	request.CreateAttr("xmlns:samlp", "urn:oasis:names:tc:SAML:2.0:protocol")
+	request.CreateAttr("xmlns:saml", "urn:oasis:names:tc:SAML:2.0:assertion")
+	request.CreateAttr("ID", "_"+hex.EncodeToString(id))
+	request.CreateAttr("Version", "2.0")
+	request.CreateAttr("IssueInstant", now.UTC().Format(time.RFC3339))
+	request.CreateAttr("Destination", destination)
+
+	request.CreateElement("saml:Issuer").SetText(issuer)
+
+	nameID := request.CreateElement("saml:NameID")
+	if subject.NameIDFormat != "" {
+		nameID.CreateAttr("Format", subject.NameIDFormat)
+	}
+	nameID.SetText(subject.NameID)
+
+	if sessionIndex != "" {
+		request.CreateElement("samlp:SessionIndex").SetText(sessionIndex)
+	}
+
+	return doc.WriteToBytes()
+}
+
+// LogoutRedirectURL encode the logout request for the HTTP-Redirect binding, signing the query when given a key
+func LogoutRedirectURL(sloURL string, request []byte, key *rsa.PrivateKey) (string, error) {
+	buf := new(bytes.Buffer)
+
+	w, err := flate.NewWriter(buf, flate.DefaultCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(request); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	// the signature covers the query parameters in this order, so they can't be built with url.Values
+	query := "SAMLRequest=" + url.QueryEscape(base64.StdEncoding.EncodeToString(buf.Bytes()))
+
+	if key != nil {
+		query += "&SigAlg=" + url.QueryEscape(signatureAlgorithmRSASHA256)
+
+		digest := sha256.Sum256([]byte(query))
+
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			return "", errors.Wrap(err, "failed to sign logout request")
+		}
+
+		query += "&Signature=" + url.QueryEscape(base64.StdEncoding.EncodeToString(signature))
+	}
+
+	separator := "?"
+	if strings.Contains(sloURL, "?") {
+		separator = "&"
+	}
+
+	return sloURL + separator + query, nil
+}
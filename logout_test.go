@@ -0,0 +1,83 @@
+package saml2aws
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/versent/saml2aws/pkg/cfg"
+)
+
+const testSLOMetadata = `<EntityDescriptor xmlns="urn:oasis:names:tc:SAML:2.0:metadata" entityID="https://id.example.com">
+  <IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <SingleLogoutService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="https://id.example.com/slo/post"/>
+    <SingleLogoutService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="https://id.example.com/slo/redirect"/>
+  </IDPSSODescriptor>
+</EntityDescriptor>`
+
+func TestExtractMetadataSLOEndpoint(t *testing.T) {
+	endpoint, err := ExtractMetadataSLOEndpoint([]byte(testSLOMetadata))
+	require.Nil(t, err)
+	assert.Equal(t, "https://id.example.com/slo/redirect", endpoint)
+
+	_, err = ExtractMetadataSLOEndpoint([]byte(`<EntityDescriptor><IDPSSODescriptor/></EntityDescriptor>`))
+	assert.Equal(t, ErrNoSLOEndpoint, err)
+}
+
+func TestLocateSLOEndpoint(t *testing.T) {
+	endpoint, err := LocateSLOEndpoint(&cfg.IDPAccount{SLOURL: "https://id.example.com/logout"})
+	require.Nil(t, err)
+	assert.Equal(t, "https://id.example.com/logout", endpoint)
+
+	_, err = LocateSLOEndpoint(&cfg.IDPAccount{})
+	assert.Equal(t, ErrNoSLOEndpoint, err)
+}
+
+func TestLogoutRedirectURL(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+
+	subject := SAMLSubject{NameID: "wolfeidau", NameIDFormat: "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"}
+
+	request, err := BuildLogoutRequest("urn:amazon:webservices", "https://id.example.com/slo", subject, "_session", time.Now())
+	require.Nil(t, err)
+
+	redirectURL, err := LogoutRedirectURL("https://id.example.com/slo", request, key)
+	require.Nil(t, err)
+
+	u, err := url.Parse(redirectURL)
+	require.Nil(t, err)
+	assert.Equal(t, "/slo", u.Path)
+
+	deflated, err := base64.StdEncoding.DecodeString(u.Query().Get("SAMLRequest"))
+	require.Nil(t, err)
+
+	inflated, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(deflated)))
+	require.Nil(t, err)
+
+	doc := etree.NewDocument()
+	require.Nil(t, doc.ReadFromBytes(inflated))
+	assert.Equal(t, "https://id.example.com/slo", doc.Root().SelectAttrValue("Destination", ""))
+	assert.Equal(t, "urn:amazon:webservices", doc.FindElement("//Issuer").Text())
+	assert.Equal(t, "wolfeidau", doc.FindElement("//NameID").Text())
+	assert.Equal(t, "_session", doc.FindElement("//SessionIndex").Text())
+
+	signed := redirectURL[strings.Index(redirectURL, "?")+1 : strings.Index(redirectURL, "&Signature=")]
+	digest := sha256.Sum256([]byte(signed))
+
+	signature, err := base64.StdEncoding.DecodeString(u.Query().Get("Signature"))
+	require.Nil(t, err)
+	assert.Nil(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+}
@@ -0,0 +1,42 @@
+package atomicfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// WriteFile write the data to a temporary file next to the file and rename it into place, so readers never see the
+// file half written, the file is readable only by the current user and its directory is created if needed
+func WriteFile(filename string, data []byte) error {
+	dir := filepath.Dir(filename)
+
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create %s directory", dir)
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(filename)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "unable to create temporary file")
+	}
+	defer os.Remove(tmp.Name())
+
+	err = tmp.Chmod(0600)
+	if err == nil {
+		_, err = tmp.Write(data)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "unable to write temporary file")
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
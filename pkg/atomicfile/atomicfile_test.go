@@ -0,0 +1,35 @@
+package atomicfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomicfile")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "nested", "file")
+
+	require.Nil(t, WriteFile(filename, []byte("first")))
+	require.Nil(t, WriteFile(filename, []byte("second")))
+
+	data, err := ioutil.ReadFile(filename)
+	require.Nil(t, err)
+	assert.Equal(t, "second", string(data))
+
+	info, err := os.Stat(filename)
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// the temporary files are cleaned up
+	files, err := ioutil.ReadDir(filepath.Dir(filename))
+	require.Nil(t, err)
+	assert.Len(t, files, 1)
+}
@@ -1,6 +1,7 @@
 package awsconfig
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path"
@@ -34,6 +35,34 @@ type AWSCredentials struct {
 	AWSSecurityToken string    `ini:"aws_security_token"`
 	PrincipalARN     string    `ini:"x_principal_arn"`
 	Expires          time.Time `ini:"x_security_token_expires"`
+	RoleSessionName  string    `ini:"x_role_session_name"`
+	SourceIdentity   string    `ini:"x_source_identity"`
+	IdpAccount       string    `ini:"x_saml2aws_idp_account"` // the idp account which logged in, so logout finds every profile it wrote
+}
+
+// credentialProcessOutput the document read from the stdout of a credential_process by the AWS SDKs
+type credentialProcessOutput struct {
+	Version         int
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      string `json:",omitempty"`
+}
+
+// CredentialProcessJSON encode the credentials in the format expected from a credential_process
+func (c *AWSCredentials) CredentialProcessJSON() ([]byte, error) {
+	output := credentialProcessOutput{
+		Version:         1,
+		AccessKeyID:     c.AWSAccessKey,
+		SecretAccessKey: c.AWSSecretKey,
+		SessionToken:    c.AWSSessionToken,
+	}
+
+	if !c.Expires.IsZero() {
+		output.Expiration = c.Expires.UTC().Format(time.RFC3339)
+	}
+
+	return json.Marshal(output)
 }
 
 // CredentialsProvider loads aws credentials file
@@ -74,14 +103,6 @@ func (p *CredentialsProvider) Save(awsCreds *AWSCredentials) error {
 		return err
 	}
 
-	err = p.ensureConfigExists()
-	if err != nil {
-		if os.IsNotExist(err) {
-			return createAndSaveProfile(filename, p.Profile, awsCreds)
-		}
-		return errors.Wrap(err, "unable to load file")
-	}
-
 	return saveProfile(filename, p.Profile, awsCreds)
 }
 
@@ -112,6 +133,57 @@ func (p *CredentialsProvider) Load() (*AWSCredentials, error) {
 	return awsCreds, nil
 }
 
+// Delete remove the profile from the credentials file
+func (p *CredentialsProvider) Delete() error {
+	filename, err := p.resolveFilename()
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return nil
+	}
+
+	return updateINIFile(filename, ini.LoadOptions{}, func(config *ini.File) error {
+		config.DeleteSection(p.Profile)
+		return nil
+	})
+}
+
+// DeleteIdpAccountProfiles remove every profile holding credentials of the idp account from the credentials file,
+// returning the names of the removed profiles
+func (p *CredentialsProvider) DeleteIdpAccountProfiles(idpAccount string) ([]string, error) {
+	filename, err := p.resolveFilename()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var profiles []string
+
+	err = updateINIFile(filename, ini.LoadOptions{}, func(config *ini.File) error {
+		profiles = nil
+
+		for _, section := range config.Sections() {
+			key, err := section.GetKey("x_saml2aws_idp_account")
+			if err == nil && key.String() == idpAccount {
+				profiles = append(profiles, section.Name())
+			}
+		}
+
+		for _, profile := range profiles {
+			config.DeleteSection(profile)
+		}
+
+		return nil
+	})
+
+	return profiles, err
+}
+
 // Expired checks if the current credentials are expired
 func (p *CredentialsProvider) Expired() bool {
 	creds, err := p.Load()
@@ -215,37 +287,13 @@ func resolveSymlink(filename string) (string, error) {
 	return sympath, nil
 }
 
-func createAndSaveProfile(filename, profile string, awsCreds *AWSCredentials) error {
-
-	dirPath := filepath.Dir(filename)
-
-	err := os.Mkdir(dirPath, 0700)
-	if err != nil {
-		return errors.Wrapf(err, "unable to create %s directory", dirPath)
-	}
-
-	_, err = os.Create(filename)
-	if err != nil {
-		return errors.Wrapf(err, "unable to create configuration")
-	}
-
-	return saveProfile(filename, profile, awsCreds)
-}
-
 func saveProfile(filename, profile string, awsCreds *AWSCredentials) error {
-	config, err := ini.Load(filename)
-	if err != nil {
-		return err
-	}
-	iniProfile, err := config.NewSection(profile)
-	if err != nil {
-		return err
-	}
-
-	err = iniProfile.ReflectFrom(awsCreds)
-	if err != nil {
-		return err
-	}
+	return updateINIFile(filename, ini.LoadOptions{}, func(config *ini.File) error {
+		iniProfile, err := config.NewSection(profile)
+		if err != nil {
+			return err
+		}
 
-	return config.SaveTo(filename)
+		return reflectCredentials(iniProfile, awsCreds)
+	})
 }
@@ -3,6 +3,7 @@ package awsconfig
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -37,4 +38,78 @@ func TestUpdateSamlConfig(t *testing.T) {
 	assert.Equal(t, "testtoken", awsCreds.AWSSessionToken)
 
 	os.Remove(".credentials")
+	os.Remove(".credentials.lock")
+}
+
+func TestDeleteProfile(t *testing.T) {
+	os.Remove(".credentials")
+
+	sharedCreds := &CredentialsProvider{".credentials", "saml"}
+	otherCreds := &CredentialsProvider{".credentials", "other"}
+
+	assert.Nil(t, sharedCreds.Delete())
+
+	assert.Nil(t, sharedCreds.Save(&AWSCredentials{AWSAccessKey: "testid"}))
+	assert.Nil(t, otherCreds.Save(&AWSCredentials{AWSAccessKey: "otherid"}))
+
+	assert.Nil(t, sharedCreds.Delete())
+
+	_, err := sharedCreds.Load()
+	assert.Equal(t, ErrCredentialsNotFound, err)
+
+	awsCreds, err := otherCreds.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "otherid", awsCreds.AWSAccessKey)
+
+	os.Remove(".credentials")
+	os.Remove(".credentials.lock")
+}
+
+func TestCredentialProcessJSON(t *testing.T) {
+	awsCreds := &AWSCredentials{
+		AWSAccessKey:    "testid",
+		AWSSecretKey:    "testsecret",
+		AWSSessionToken: "testtoken",
+		Expires:         time.Date(2019, 8, 19, 15, 0, 56, 0, time.UTC),
+	}
+
+	data, err := awsCreds.CredentialProcessJSON()
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{
+		"Version": 1,
+		"AccessKeyId": "testid",
+		"SecretAccessKey": "testsecret",
+		"SessionToken": "testtoken",
+		"Expiration": "2019-08-19T15:00:56Z"
+	}`, string(data))
+}
+
+func TestDeleteIdpAccountProfiles(t *testing.T) {
+	os.Remove(".credentials")
+
+	sharedCreds := &CredentialsProvider{".credentials", "saml"}
+
+	profiles, err := sharedCreds.DeleteIdpAccountProfiles("default")
+	assert.Nil(t, err)
+	assert.Empty(t, profiles)
+
+	assert.Nil(t, (&CredentialsProvider{".credentials", "saml"}).Save(&AWSCredentials{AWSAccessKey: "testid", IdpAccount: "default"}))
+	assert.Nil(t, (&CredentialsProvider{".credentials", "000000000001-ReadOnly"}).Save(&AWSCredentials{AWSAccessKey: "roleid", IdpAccount: "default"}))
+	assert.Nil(t, (&CredentialsProvider{".credentials", "other"}).Save(&AWSCredentials{AWSAccessKey: "otherid", IdpAccount: "other"}))
+	assert.Nil(t, (&CredentialsProvider{".credentials", "manual"}).Save(&AWSCredentials{AWSAccessKey: "manualid"}))
+
+	profiles, err = sharedCreds.DeleteIdpAccountProfiles("default")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"saml", "000000000001-ReadOnly"}, profiles)
+
+	_, err = sharedCreds.Load()
+	assert.Equal(t, ErrCredentialsNotFound, err)
+
+	for _, profile := range []string{"other", "manual"} {
+		_, err = (&CredentialsProvider{".credentials", profile}).Load()
+		assert.Nil(t, err)
+	}
+
+	os.Remove(".credentials")
+	os.Remove(".credentials.lock")
 }
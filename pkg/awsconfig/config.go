@@ -0,0 +1,111 @@
+package awsconfig
+
+import (
+	"os"
+	"path"
+	"runtime"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+
+	ini "gopkg.in/ini.v1"
+)
+
+// ConfigProfile represents the settings of a profile in the aws cli config file, empty settings are left unchanged
+type ConfigProfile struct {
+	Region        string
+	Output        string
+	RoleARN       string
+	SourceProfile string
+}
+
+// ConfigProvider updates profiles in the aws cli config file
+type ConfigProvider struct {
+	Filename string
+}
+
+// NewSharedConfig helper to create the config provider
+func NewSharedConfig() *ConfigProvider {
+	return &ConfigProvider{}
+}
+
+// Save create or update the profile, leaving the other profiles and settings in the file as they are
+func (p *ConfigProvider) Save(profile string, configProfile *ConfigProfile) error {
+	filename, err := p.resolveFilename()
+	if err != nil {
+		return err
+	}
+
+	return updateINIFile(filename, ini.LoadOptions{AllowNestedValues: true}, func(config *ini.File) error {
+		section := config.Section(ConfigSectionName(profile))
+
+		settings := []struct {
+			key   string
+			value string
+		}{
+			{"region", configProfile.Region},
+			{"output", configProfile.Output},
+			{"role_arn", configProfile.RoleARN},
+			{"source_profile", configProfile.SourceProfile},
+		}
+
+		for _, setting := range settings {
+			if setting.value != "" {
+				section.Key(setting.key).SetValue(setting.value)
+			}
+		}
+
+		return nil
+	})
+}
+
+// ConfigSectionName return the name of the section holding the profile, in the config file every profile other than
+// the default one is prefixed with profile
+func ConfigSectionName(profile string) string {
+	if profile == "default" {
+		return profile
+	}
+
+	return "profile " + profile
+}
+
+func (p *ConfigProvider) resolveFilename() (string, error) {
+	if p.Filename == "" {
+		filename, err := locateAWSConfigFile()
+		if err != nil {
+			return "", err
+		}
+
+		p.Filename = filename
+	}
+
+	return p.Filename, nil
+}
+
+func locateAWSConfigFile() (string, error) {
+
+	filename := os.Getenv("AWS_CONFIG_FILE")
+
+	if filename != "" {
+		return filename, nil
+	}
+
+	var name string
+	var err error
+	if runtime.GOOS == "windows" {
+		name = path.Join(os.Getenv("USERPROFILE"), ".aws", "config")
+	} else {
+		name, err = homedir.Expand("~/.aws/config")
+		if err != nil {
+			return "", ErrCredentialsHomeNotFound
+		}
+	}
+
+	// is the filename a symlink?
+	name, err = resolveSymlink(name)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to resolve symlink")
+	}
+
+	return name, nil
+}
@@ -0,0 +1,69 @@
+package awsconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAWSConfig = `# managed by hand
+[default]
+region = ap-southeast-2
+
+[profile other]
+# keep this comment
+region = eu-west-1
+s3 =
+  max_concurrent_requests = 20
+`
+
+func TestSaveConfigProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awsconfig")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "config")
+	require.Nil(t, ioutil.WriteFile(filename, []byte(testAWSConfig), 0600))
+
+	sharedConfig := &ConfigProvider{Filename: filename}
+
+	err = sharedConfig.Save("saml", &ConfigProfile{Region: "us-east-1", Output: "json"})
+	require.Nil(t, err)
+
+	err = sharedConfig.Save("prod", &ConfigProfile{RoleARN: "arn:aws:iam::000000000002:role/Admin", SourceProfile: "saml"})
+	require.Nil(t, err)
+
+	// empty settings leave the existing ones alone
+	err = sharedConfig.Save("default", &ConfigProfile{Output: "text"})
+	require.Nil(t, err)
+
+	data, err := ioutil.ReadFile(filename)
+	require.Nil(t, err)
+	config := string(data)
+
+	assert.Contains(t, config, "# managed by hand")
+	assert.Contains(t, config, "# keep this comment")
+	assert.Contains(t, config, "max_concurrent_requests = 20")
+	assert.Contains(t, config, "[profile saml]\nregion = us-east-1\noutput = json\n")
+	assert.Regexp(t, `\[profile prod\]\nrole_arn\s+= arn:aws:iam::000000000002:role/Admin\nsource_profile = saml\n`, config)
+	assert.Contains(t, config, "[default]\nregion = ap-southeast-2\noutput = text\n")
+}
+
+func TestSaveConfigProfileNewFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awsconfig")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, ".aws", "config")
+
+	err = (&ConfigProvider{Filename: filename}).Save("saml", &ConfigProfile{Region: "us-east-1"})
+	require.Nil(t, err)
+
+	data, err := ioutil.ReadFile(filename)
+	require.Nil(t, err)
+	assert.Contains(t, string(data), "[profile saml]\nregion = us-east-1\n")
+}
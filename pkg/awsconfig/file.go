@@ -0,0 +1,90 @@
+package awsconfig
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/versent/saml2aws/pkg/atomicfile"
+	"github.com/versent/saml2aws/pkg/flock"
+
+	ini "gopkg.in/ini.v1"
+)
+
+// updateINIFile apply the update to the file while holding its lock so concurrent saml2aws processes don't clobber
+// each other, the file is replaced atomically so readers never see it half written
+func updateINIFile(filename string, opts ini.LoadOptions, update func(config *ini.File) error) error {
+	// write through a symlinked file rather than replacing the link with a regular file
+	filename, err := resolveSymlink(filename)
+	if err != nil {
+		return errors.Wrap(err, "unable to resolve symlink")
+	}
+
+	lock, err := flock.Acquire(filename + ".lock")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	// the file may not exist yet
+	opts.Loose = true
+
+	config, err := ini.LoadSources(opts, filename)
+	if err != nil {
+		return errors.Wrapf(err, "unable to load file %s", filename)
+	}
+
+	err = update(config)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+
+	_, err = config.WriteTo(buf)
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(filename, buf.Bytes())
+}
+
+// credentialKeys the keys saml2aws writes to a credentials profile
+func credentialKeys() map[string]bool {
+	keys := map[string]bool{}
+
+	t := reflect.TypeOf(AWSCredentials{})
+	for i := 0; i < t.NumField(); i++ {
+		if name := strings.Split(t.Field(i).Tag.Get("ini"), ",")[0]; name != "" {
+			keys[name] = true
+		}
+	}
+
+	return keys
+}
+
+// reflectCredentials write the credentials to the section, dropping keys saml2aws wrote previously which are now
+// empty, keys added by hand are kept
+func reflectCredentials(section *ini.Section, awsCreds *AWSCredentials) error {
+	managed := credentialKeys()
+
+	for _, key := range section.KeyStrings() {
+		if managed[key] {
+			section.DeleteKey(key)
+		}
+	}
+
+	err := section.ReflectFrom(awsCreds)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range section.Keys() {
+		if managed[key.Name()] && key.Value() == "" {
+			section.DeleteKey(key.Name())
+		}
+	}
+
+	return nil
+}
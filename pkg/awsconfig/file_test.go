@@ -0,0 +1,103 @@
+package awsconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveConcurrently(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awsconfig")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "credentials")
+	require.Nil(t, ioutil.WriteFile(filename, []byte("# managed by saml2aws\n[static]\naws_access_key_id = static\n"), 0600))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 25; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			sharedCreds := &CredentialsProvider{Filename: filename, Profile: fmt.Sprintf("profile-%d", i)}
+			err := sharedCreds.Save(&AWSCredentials{
+				AWSAccessKey: fmt.Sprintf("key-%d", i),
+				Expires:      time.Now().Add(time.Hour),
+			})
+			assert.Nil(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 25; i++ {
+		awsCreds, err := (&CredentialsProvider{Filename: filename, Profile: fmt.Sprintf("profile-%d", i)}).Load()
+		require.Nil(t, err)
+		assert.Equal(t, fmt.Sprintf("key-%d", i), awsCreds.AWSAccessKey)
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	require.Nil(t, err)
+	assert.Contains(t, string(data), "# managed by saml2aws")
+	assert.Contains(t, string(data), "aws_access_key_id = static")
+
+	info, err := os.Stat(filename)
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// only the credentials file and its lock are left behind
+	files, err := ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	assert.Len(t, files, 2)
+}
+
+func TestSaveRemovesStaleKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awsconfig")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "credentials")
+	require.Nil(t, ioutil.WriteFile(filename, []byte("[saml]\nregion = us-east-1\nx_source_identity = old\nx_legacy = old\n"), 0600))
+
+	sharedCreds := &CredentialsProvider{Filename: filename, Profile: "saml"}
+	require.Nil(t, sharedCreds.Save(&AWSCredentials{AWSAccessKey: "key"}))
+
+	data, err := ioutil.ReadFile(filename)
+	require.Nil(t, err)
+	assert.Contains(t, string(data), "region")
+	assert.Contains(t, string(data), "aws_access_key_id")
+	assert.NotContains(t, string(data), "x_source_identity")
+	assert.Contains(t, string(data), "x_legacy")
+	assert.NotContains(t, string(data), "aws_session_token")
+}
+
+func TestSaveKeepsSymlink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awsconfig")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	target := filepath.Join(dir, "shared-credentials")
+	require.Nil(t, ioutil.WriteFile(target, []byte("[static]\naws_access_key_id = static\n"), 0600))
+
+	filename := filepath.Join(dir, "credentials")
+	require.Nil(t, os.Symlink(target, filename))
+
+	sharedCreds := &CredentialsProvider{Filename: filename, Profile: "saml"}
+	require.Nil(t, sharedCreds.Save(&AWSCredentials{AWSAccessKey: "key"}))
+
+	info, err := os.Lstat(filename)
+	require.Nil(t, err)
+	assert.True(t, info.Mode()&os.ModeSymlink != 0)
+
+	data, err := ioutil.ReadFile(target)
+	require.Nil(t, err)
+	assert.Contains(t, string(data), "aws_access_key_id = static")
+	assert.Contains(t, string(data), "[saml]")
+}
@@ -3,9 +3,13 @@ package cfg
 import (
 	"fmt"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
+	"github.com/versent/saml2aws/pkg/partition"
 	ini "gopkg.in/ini.v1"
 )
 
@@ -24,25 +28,78 @@ const (
 	// see https://aws.amazon.com/blogs/security/enable-federated-api-access-to-your-aws-resources-for-up-to-12-hours-using-iam-roles/
 	DefaultSessionDuration = 3600
 
+	// DefaultSessionDurationLadder the session durations tried in turn when the session duration is set to auto
+	DefaultSessionDurationLadder = "12h,8h,4h,1h"
+
+	// DefaultAccountNameResolvers the order in which account names are looked up
+	DefaultAccountNameResolvers = "aliases,cache,signin"
+
+	// AccountAliasesSection the section of the configuration file which maps account ids to names
+	AccountAliasesSection = "account_aliases"
+
+	// RoleProfilesSection the section of the configuration file which names the profiles of roles selected with --roles
+	RoleProfilesSection = "role_profiles"
+
+	// RoleProfilesSectionPrefix the prefix of the sections mapping profile names to the roles of an idp account which
+	// are logged in together
+	RoleProfilesSectionPrefix = "role_profiles:"
+
+	// RoleAliasesSection the section of the configuration file which names roles
+	RoleAliasesSection = "roles"
+
+	// GroupSectionPrefix the prefix of the sections naming groups of idp accounts logged in together
+	GroupSectionPrefix = "group:"
+
 	// DefaultProfile this is the default profile name used to save the credentials in the aws cli
 	DefaultProfile = "saml"
+
+	// DefaultRequestTimeout the seconds allowed for each attempt of a request to the IdP, long enough for the IdPs which
+	// hold the request open while waiting for an MFA push to be approved
+	DefaultRequestTimeout = 120
+
+	// DefaultHTTPRetries the retries of safe requests to the IdP failing with transient errors
+	DefaultHTTPRetries = 2
 )
 
 // IDPAccount saml IDP account
 type IDPAccount struct {
-	AppID                string `ini:"app_id"` // used by OneLogin and AzureAD
-	URL                  string `ini:"url"`
-	Username             string `ini:"username"`
-	Provider             string `ini:"provider"`
-	MFA                  string `ini:"mfa"`
-	SkipVerify           bool   `ini:"skip_verify"`
-	Timeout              int    `ini:"timeout"`
-	AmazonWebservicesURN string `ini:"aws_urn"`
-	SessionDuration      int    `ini:"aws_session_duration"`
-	Profile              string `ini:"aws_profile"`
-	ResourceID           string `ini:"resource_id"` // used by F5APM
-	Subdomain            string `ini:"subdomain"`   // used by OneLogin
-	RoleARN              string `ini:"role_arn"`
+	AppID                 string `ini:"app_id"` // used by OneLogin and AzureAD
+	URL                   string `ini:"url"`
+	Username              string `ini:"username"`
+	Provider              string `ini:"provider"`
+	MFA                   string `ini:"mfa"`
+	SkipVerify            bool   `ini:"skip_verify"`
+	Timeout               int    `ini:"timeout"`         // seconds allowed for a request to the IdP including retries, 0 for no limit
+	RequestTimeout        int    `ini:"request_timeout"` // seconds allowed for each attempt of a request to the IdP, 0 for no limit
+	HTTPRetries           int    `ini:"http_retries"`    // retries of safe requests to the IdP failing with transient errors
+	AmazonWebservicesURN  string `ini:"aws_urn"`
+	SessionDuration       int    `ini:"aws_session_duration"`
+	SessionDurationAuto   bool   `ini:"aws_session_duration_auto"`   // negotiate the duration with the IdP and STS
+	SessionDurationLadder string `ini:"aws_session_duration_ladder"` // durations tried in auto mode, e.g. 12h,8h,4h,1h
+	Profile               string `ini:"aws_profile"`
+	ResourceID            string `ini:"resource_id"` // used by F5APM
+	Subdomain             string `ini:"subdomain"`   // used by OneLogin
+	RoleARN               string `ini:"role_arn"`
+	IDPCertificate        string `ini:"idp_certificate"`        // PEM file used to verify the assertion signature
+	IDPMetadataFile       string `ini:"idp_metadata_file"`      // SAML metadata used to verify the assertion signature
+	SPPrivateKey          string `ini:"sp_private_key"`         // PEM file used to decrypt encrypted assertions
+	AssertionCache        string `ini:"assertion_cache"`        // file (default), keychain or none
+	CookieCache           string `ini:"cookie_cache"`           // none (default), file or keychain
+	SLOURL                string `ini:"slo_url"`                // SAML single logout endpoint used by logout --slo
+	AccountNameResolvers  string `ini:"account_name_resolvers"` // order of aliases, cache, signin and iam
+	Region                string `ini:"aws_region"`             // region written to the profile in ~/.aws/config
+	Output                string `ini:"aws_output"`             // output format written to the profile in ~/.aws/config
+	ChainedProfiles       string `ini:"aws_chained_profiles"`   // profiles assuming roles from this one, e.g. prod=arn:aws:iam::...
+	AssumeChain           string `ini:"assume_chain"`           // roles assumed in turn after logging in, see ParseAssumeChain
+	CABundle              string `ini:"ca_bundle"`              // PEM file of CAs trusted for the IdP along with the system ones
+	ClientCert            string `ini:"client_cert"`            // PEM file of the certificate presented to IdPs requiring mutual TLS
+	ClientKey             string `ini:"client_key"`             // PEM file of the private key of client_cert
+}
+
+// ChainedProfile a profile in ~/.aws/config assuming a role using the credentials of the idp account profile
+type ChainedProfile struct {
+	Profile string
+	RoleARN string
 }
 
 func (ia IDPAccount) String() string {
@@ -114,15 +171,63 @@ func (ia *IDPAccount) Validate() error {
 		return errors.New("Profile empty in idp account")
 	}
 
+	switch ia.AssertionCache {
+	case "", "file", "keychain", "none":
+	default:
+		return errors.New("assertion cache must be one of file, keychain or none")
+	}
+
 	return nil
 }
 
+// ApplyPartitionURN use the URN of the partition of the role when the URN is left at the default, as roles outside the
+// commercial partition federate through their own URN
+func (ia *IDPAccount) ApplyPartitionURN() {
+	if ia.AmazonWebservicesURN != DefaultAmazonWebservicesURN || ia.RoleARN == "" {
+		return
+	}
+
+	if p, err := partition.FromARN(ia.RoleARN); err == nil {
+		ia.AmazonWebservicesURN = p.URN
+	}
+}
+
+// ParseChainedProfiles parse the comma separated profile=role_arn pairs of aws_chained_profiles
+func (ia *IDPAccount) ParseChainedProfiles() ([]ChainedProfile, error) {
+	var chained []ChainedProfile
+
+	for _, pair := range strings.Split(ia.ChainedProfiles, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, errors.Errorf("invalid chained profile %q, expected profile=role_arn", pair)
+		}
+
+		chained = append(chained, ChainedProfile{Profile: strings.TrimSpace(parts[0]), RoleARN: strings.TrimSpace(parts[1])})
+	}
+
+	return chained, nil
+}
+
+// RoleAlias a named role from the roles section along with the profile and session duration used when logging into it
+type RoleAlias struct {
+	Role            string
+	Profile         string
+	SessionDuration int
+}
+
 // NewIDPAccount Create an idp account and fill in any default fields with sane values
 func NewIDPAccount() *IDPAccount {
 	return &IDPAccount{
 		AmazonWebservicesURN: DefaultAmazonWebservicesURN,
 		SessionDuration:      DefaultSessionDuration,
 		Profile:              DefaultProfile,
+		RequestTimeout:       DefaultRequestTimeout,
+		HTTPRetries:          DefaultHTTPRetries,
 	}
 }
 
@@ -193,6 +298,211 @@ func (cm *ConfigManager) LoadIDPAccount(idpAccountName string) (*IDPAccount, err
 	return account, nil
 }
 
+// ListIDPAccounts list the names of the idp accounts in the configuration file
+func (cm *ConfigManager) ListIDPAccounts() ([]string, error) {
+
+	cfg, err := ini.LoadSources(ini.LoadOptions{Loose: true}, cm.configPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to load configuration file")
+	}
+
+	names := []string{}
+
+	for _, name := range cfg.SectionStrings() {
+		switch name {
+		case ini.DEFAULT_SECTION, AccountAliasesSection, RoleProfilesSection, RoleAliasesSection:
+			continue
+		}
+		if strings.HasPrefix(name, GroupSectionPrefix) || strings.HasPrefix(name, RoleProfilesSectionPrefix) {
+			continue
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// LoadIDPAccountGroup load the names of the idp accounts in the comma separated accounts key of the group section
+func (cm *ConfigManager) LoadIDPAccountGroup(group string) ([]string, error) {
+
+	cfg, err := ini.LoadSources(ini.LoadOptions{Loose: true}, cm.configPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to load configuration file")
+	}
+
+	section, err := cfg.GetSection(GroupSectionPrefix + group)
+	if err != nil {
+		return nil, errors.Errorf("idp account group %s not found", group)
+	}
+
+	names := []string{}
+
+	for _, name := range section.Key("accounts").Strings(",") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, errors.Errorf("idp account group %s has no accounts", group)
+	}
+
+	return names, nil
+}
+
+// LoadAccountAliases load the account id to name mappings from the account_aliases section
+func (cm *ConfigManager) LoadAccountAliases() (map[string]string, error) {
+
+	cfg, err := ini.LoadSources(ini.LoadOptions{Loose: true}, cm.configPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to load configuration file")
+	}
+
+	return cfg.Section(AccountAliasesSection).KeysHash(), nil
+}
+
+// LoadRoleProfiles load the profile name to role ARN or pattern mappings from the role_profiles section
+func (cm *ConfigManager) LoadRoleProfiles() (map[string]string, error) {
+
+	cfg, err := ini.LoadSources(ini.LoadOptions{Loose: true}, cm.configPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to load configuration file")
+	}
+
+	return cfg.Section(RoleProfilesSection).KeysHash(), nil
+}
+
+// LoadIDPAccountRoleProfiles load the profile name to role ARN or pattern mappings of the idp account from its
+// role_profiles:<idp account> section
+func (cm *ConfigManager) LoadIDPAccountRoleProfiles(idpAccountName string) (map[string]string, error) {
+
+	cfg, err := ini.LoadSources(ini.LoadOptions{Loose: true}, cm.configPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to load configuration file")
+	}
+
+	return cfg.Section(RoleProfilesSectionPrefix + idpAccountName).KeysHash(), nil
+}
+
+// LoadRoleAliases load the named roles from the roles section, each value is a role ARN or pattern optionally followed by
+// profile=<name> and aws_session_duration=<duration>
+func (cm *ConfigManager) LoadRoleAliases() (map[string]*RoleAlias, error) {
+
+	cfg, err := ini.LoadSources(ini.LoadOptions{Loose: true}, cm.configPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to load configuration file")
+	}
+
+	aliases := map[string]*RoleAlias{}
+
+	for name, value := range cfg.Section(RoleAliasesSection).KeysHash() {
+		alias, err := parseRoleAlias(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to read role alias %s", name)
+		}
+		aliases[name] = alias
+	}
+
+	return aliases, nil
+}
+
+func parseRoleAlias(value string) (*RoleAlias, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return nil, errors.New("role missing")
+	}
+
+	alias := &RoleAlias{Role: fields[0]}
+
+	for _, field := range fields[1:] {
+		tokens := strings.SplitN(field, "=", 2)
+		if len(tokens) != 2 {
+			return nil, errors.Errorf("invalid option %s, expected name=value", field)
+		}
+
+		switch tokens[0] {
+		case "profile":
+			alias.Profile = tokens[1]
+		case "aws_session_duration":
+			seconds, err := parseSessionDuration(tokens[1])
+			if err != nil {
+				return nil, err
+			}
+			alias.SessionDuration = seconds
+		default:
+			return nil, errors.Errorf("unknown option %s, expected profile or aws_session_duration", tokens[0])
+		}
+	}
+
+	return alias, nil
+}
+
+// ChainHop a role assumed with the credentials of the previous hop, after logging in with the SAML assertion
+type ChainHop struct {
+	RoleARN         string
+	ExternalID      string
+	SessionName     string
+	SessionDuration int
+	Profile         string // also save the credentials of this hop to the profile
+}
+
+// ParseAssumeChain parse the comma separated hops of assume_chain, each a role ARN followed by the options
+// external_id, session_name, aws_session_duration and profile
+func (ia *IDPAccount) ParseAssumeChain() ([]ChainHop, error) {
+	var hops []ChainHop
+
+	for _, value := range strings.Split(ia.AssumeChain, ",") {
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+
+		hop := ChainHop{RoleARN: fields[0]}
+
+		for _, field := range fields[1:] {
+			tokens := strings.SplitN(field, "=", 2)
+			if len(tokens) != 2 {
+				return nil, errors.Errorf("invalid option %s, expected name=value", field)
+			}
+
+			switch tokens[0] {
+			case "external_id":
+				hop.ExternalID = tokens[1]
+			case "session_name":
+				hop.SessionName = tokens[1]
+			case "profile":
+				hop.Profile = tokens[1]
+			case "aws_session_duration":
+				seconds, err := parseSessionDuration(tokens[1])
+				if err != nil {
+					return nil, err
+				}
+				hop.SessionDuration = seconds
+			default:
+				return nil, errors.Errorf("unknown option %s, expected external_id, session_name, aws_session_duration or profile", tokens[0])
+			}
+		}
+
+		hops = append(hops, hop)
+	}
+
+	return hops, nil
+}
+
+// parseSessionDuration parse a session duration given in seconds or as a duration such as 8h
+func parseSessionDuration(value string) (int, error) {
+	seconds, err := strconv.Atoi(value)
+	if err != nil {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, errors.Errorf("invalid session duration %s", value)
+		}
+		seconds = int(d.Seconds())
+	}
+
+	return seconds, nil
+}
+
 func readAccount(idpAccountName string, cfg *ini.File) (*IDPAccount, error) {
 
 	account := NewIDPAccount()
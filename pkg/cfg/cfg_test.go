@@ -34,6 +34,8 @@ func TestNewConfigManagerLoad(t *testing.T) {
 		AmazonWebservicesURN: DefaultAmazonWebservicesURN,
 		SessionDuration:      3600,
 		Profile:              "saml",
+		RequestTimeout:       DefaultRequestTimeout,
+		HTTPRetries:          DefaultHTTPRetries,
 	}, idpAccount)
 
 	idpAccount, err = cfgm.LoadIDPAccount("")
@@ -42,6 +44,8 @@ func TestNewConfigManagerLoad(t *testing.T) {
 		AmazonWebservicesURN: DefaultAmazonWebservicesURN,
 		SessionDuration:      3600,
 		Profile:              "saml",
+		RequestTimeout:       DefaultRequestTimeout,
+		HTTPRetries:          DefaultHTTPRetries,
 	}, idpAccount)
 }
 
@@ -72,3 +76,127 @@ func TestNewConfigManagerSave(t *testing.T) {
 	os.Remove(throwAwayConfig)
 
 }
+
+func TestNewConfigManagerLoadAccountAliases(t *testing.T) {
+
+	cfgm, err := NewConfigManager("example/saml2aws.ini")
+	require.Nil(t, err)
+
+	aliases, err := cfgm.LoadAccountAliases()
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{
+		"000000000001": "production",
+		"000000000002": "development",
+	}, aliases)
+}
+
+func TestNewConfigManagerLoadRoleProfiles(t *testing.T) {
+
+	cfgm, err := NewConfigManager("example/saml2aws.ini")
+	require.Nil(t, err)
+
+	roleProfiles, err := cfgm.LoadRoleProfiles()
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{
+		"dev":     "arn:aws:iam::000000000002:role/Development",
+		"prod-ro": "arn:aws:iam::000000000001:role/ReadOnly",
+	}, roleProfiles)
+}
+
+func TestNewConfigManagerLoadIDPAccountRoleProfiles(t *testing.T) {
+
+	cfgm, err := NewConfigManager("example/saml2aws.ini")
+	require.Nil(t, err)
+
+	roleProfiles, err := cfgm.LoadIDPAccountRoleProfiles("wolfeidau")
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{
+		"dev":    "arn:aws:iam::000000000002:role/Development",
+		"dev-ro": "arn:aws:iam::000000000002:role/ReadOnly",
+	}, roleProfiles)
+
+	roleProfiles, err = cfgm.LoadIDPAccountRoleProfiles("test123")
+	require.Nil(t, err)
+	require.Empty(t, roleProfiles)
+}
+
+func TestNewConfigManagerLoadRoleAliases(t *testing.T) {
+
+	cfgm, err := NewConfigManager("example/saml2aws.ini")
+	require.Nil(t, err)
+
+	aliases, err := cfgm.LoadRoleAliases()
+	require.Nil(t, err)
+	require.Equal(t, map[string]*RoleAlias{
+		"dev-admin": {Role: "arn:aws:iam::000000000002:role/Admin"},
+		"prod-ro":   {Role: "production/ReadOnly", Profile: "prod-ro", SessionDuration: 28800},
+	}, aliases)
+}
+
+func TestParseRoleAlias(t *testing.T) {
+
+	alias, err := parseRoleAlias("*:role/ReadOnly aws_session_duration=3600")
+	require.Nil(t, err)
+	require.Equal(t, &RoleAlias{Role: "*:role/ReadOnly", SessionDuration: 3600}, alias)
+
+	_, err = parseRoleAlias("*:role/ReadOnly region=us-east-1")
+	require.Error(t, err)
+
+	_, err = parseRoleAlias("*:role/ReadOnly aws_session_duration=forever")
+	require.Error(t, err)
+}
+
+func TestParseChainedProfiles(t *testing.T) {
+
+	account := &IDPAccount{ChainedProfiles: "prod=arn:aws:iam::000000000002:role/Admin, audit = arn:aws:iam::000000000003:role/ReadOnly"}
+
+	chained, err := account.ParseChainedProfiles()
+	require.Nil(t, err)
+	require.Equal(t, []ChainedProfile{
+		{Profile: "prod", RoleARN: "arn:aws:iam::000000000002:role/Admin"},
+		{Profile: "audit", RoleARN: "arn:aws:iam::000000000003:role/ReadOnly"},
+	}, chained)
+
+	account.ChainedProfiles = "prod"
+	_, err = account.ParseChainedProfiles()
+	require.Error(t, err)
+}
+
+func TestParseAssumeChain(t *testing.T) {
+
+	account := &IDPAccount{AssumeChain: "arn:aws:iam::000000000002:role/Hub profile=hub, arn:aws:iam::000000000003:role/Admin external_id=abc session_name=ops aws_session_duration=30m"}
+
+	hops, err := account.ParseAssumeChain()
+	require.Nil(t, err)
+	require.Equal(t, []ChainHop{
+		{RoleARN: "arn:aws:iam::000000000002:role/Hub", Profile: "hub"},
+		{RoleARN: "arn:aws:iam::000000000003:role/Admin", ExternalID: "abc", SessionName: "ops", SessionDuration: 1800},
+	}, hops)
+
+	account.AssumeChain = "arn:aws:iam::000000000002:role/Hub region=us-east-1"
+	_, err = account.ParseAssumeChain()
+	require.Error(t, err)
+}
+
+func TestNewConfigManagerListIDPAccounts(t *testing.T) {
+
+	cfgm, err := NewConfigManager("example/saml2aws.ini")
+	require.Nil(t, err)
+
+	names, err := cfgm.ListIDPAccounts()
+	require.Nil(t, err)
+	require.Equal(t, []string{"wolfeidau", "test123"}, names)
+}
+
+func TestNewConfigManagerLoadIDPAccountGroup(t *testing.T) {
+
+	cfgm, err := NewConfigManager("example/saml2aws.ini")
+	require.Nil(t, err)
+
+	names, err := cfgm.LoadIDPAccountGroup("work")
+	require.Nil(t, err)
+	require.Equal(t, []string{"wolfeidau", "test123"}, names)
+
+	_, err = cfgm.LoadIDPAccountGroup("missing")
+	require.Error(t, err)
+}
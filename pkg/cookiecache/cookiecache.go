@@ -0,0 +1,251 @@
+package cookiecache
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/versent/saml2aws/helper/credentials"
+	"github.com/versent/saml2aws/pkg/atomicfile"
+	"github.com/versent/saml2aws/pkg/flock"
+)
+
+const (
+	// DefaultCacheDir the directory holding the encrypted cookie jars when stored on disk
+	DefaultCacheDir = "~/.saml2aws.d/cookies"
+
+	// ModeFile cache cookies in files encrypted with a key readable only by the current user
+	ModeFile = "file"
+
+	// ModeKeychain cache cookies through the credentials helper
+	ModeKeychain = "keychain"
+
+	// ModeNone disable caching of cookies, the default
+	ModeNone = "none"
+
+	keyFilename = ".key"
+
+	keychainURLPrefix = "saml2aws-cookie-cache://"
+)
+
+// ErrNotFound returned when there are no cached cookies for the idp account
+var ErrNotFound = errors.New("cached cookies not found")
+
+// Entry the serialized cookie jar of an IdP session, along with the IdP and user the session belongs to
+type Entry struct {
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Cookies  []byte `json:"cookies"`
+}
+
+// Valid returns true if the session was started with the same IdP, by the same user when the username is known
+func (e *Entry) Valid(url, username string) bool {
+	if e.URL != url || len(e.Cookies) == 0 {
+		return false
+	}
+
+	return username == "" || e.Username == username
+}
+
+// Store persists the cookie jars of IdP sessions keyed by idp account name, so login and logout find the same entry
+// whichever way the username was resolved
+type Store interface {
+	Load(idpAccount string) (*Entry, error)
+	Save(idpAccount string, entry *Entry) error
+	Delete(idpAccount string) error
+}
+
+// NewStore build the store for the cookie_cache mode of the idp account, nil when cookies aren't cached
+func NewStore(mode string) (Store, error) {
+	switch mode {
+	case "", ModeNone:
+		return nil, nil
+	case ModeFile:
+		return NewFileStore(DefaultCacheDir)
+	case ModeKeychain:
+		return &KeychainStore{}, nil
+	}
+
+	return nil, errors.Errorf("unknown cookie cache %s", mode)
+}
+
+// FileStore caches cookie jars as files encrypted with AES-GCM, the key is kept in its own file so the cookie files
+// on their own, for example in a backup, don't give away the IdP session
+type FileStore struct {
+	Dir string
+}
+
+// NewFileStore build a store keeping the encrypted cookie jars and their key in the directory, which may start with ~
+func NewFileStore(dir string) (*FileStore, error) {
+	dir, err := homedir.Expand(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileStore{Dir: dir}, nil
+}
+
+// Load read and decrypt the cookie jar cached for the idp account
+func (fs *FileStore) Load(idpAccount string) (*Entry, error) {
+	data, err := ioutil.ReadFile(fs.filename(idpAccount))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrap(err, "unable to read cached cookies")
+	}
+
+	gcm, err := fs.cipher()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("cached cookies are truncated")
+	}
+
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(idpAccount))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decrypt cached cookies")
+	}
+
+	return decodeEntry(plaintext)
+}
+
+// Save encrypt and write the cookie jar of the idp account, the idp account name is authenticated along with the
+// cookies so a file can't be passed off as the session of another account
+func (fs *FileStore) Save(idpAccount string, entry *Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := fs.cipher()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(fs.filename(idpAccount), gcm.Seal(nonce, nonce, data, []byte(idpAccount)))
+}
+
+// Delete remove the cookie jar cached for the idp account
+func (fs *FileStore) Delete(idpAccount string) error {
+	err := os.Remove(fs.filename(idpAccount))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (fs *FileStore) filename(idpAccount string) string {
+	return filepath.Join(fs.Dir, filepath.Base(idpAccount)+".bin")
+}
+
+// cipher load the encryption key, generating it on first use while holding a lock so concurrent logins agree on the key
+func (fs *FileStore) cipher() (cipher.AEAD, error) {
+	filename := filepath.Join(fs.Dir, keyFilename)
+
+	key, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		key, err = fs.createKey(filename)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to load cookie cache key")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid cookie cache key")
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// createKey generate and write the key unless another process wrote it while this one waited for the lock
+func (fs *FileStore) createKey(filename string) ([]byte, error) {
+	lock, err := flock.Acquire(filename + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	key, err := ioutil.ReadFile(filename)
+	if !os.IsNotExist(err) {
+		return key, err
+	}
+
+	key = make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+
+	return key, atomicfile.WriteFile(filename, key)
+}
+
+// KeychainStore caches cookie jars through the configured credentials helper
+type KeychainStore struct{}
+
+// Load read the cookie jar cached for the idp account
+func (ks *KeychainStore) Load(idpAccount string) (*Entry, error) {
+	_, secret, err := credentials.CurrentHelper.Get(keychainURLPrefix + idpAccount)
+	if err != nil {
+		if credentials.IsErrCredentialsNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrap(err, "unable to read cached cookies")
+	}
+
+	return decodeEntry([]byte(secret))
+}
+
+// Save write the cookie jar of the idp account, replacing any existing one
+func (ks *KeychainStore) Save(idpAccount string, entry *Entry) error {
+	if !credentials.SupportsStorage() {
+		return errors.New("no credentials helper available to cache cookies")
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return credentials.CurrentHelper.Add(&credentials.Credentials{
+		ServerURL: keychainURLPrefix + idpAccount,
+		Username:  idpAccount,
+		Secret:    string(data),
+	})
+}
+
+// Delete remove the cookie jar cached for the idp account
+func (ks *KeychainStore) Delete(idpAccount string) error {
+	err := credentials.CurrentHelper.Delete(keychainURLPrefix + idpAccount)
+	if err != nil && !credentials.IsErrCredentialsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func decodeEntry(data []byte) (*Entry, error) {
+	entry := new(Entry)
+
+	err := json.Unmarshal(data, entry)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decode cached cookies")
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,98 @@
+package cookiecache
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cookiecache")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	store := &FileStore{Dir: filepath.Join(dir, "cookies")}
+
+	_, err = store.Load("wolfeidau")
+	assert.Equal(t, ErrNotFound, err)
+
+	entry := &Entry{URL: "https://id.example.com", Username: "mark", Cookies: []byte(`{"Entries":{}}`)}
+	require.Nil(t, store.Save("wolfeidau", entry))
+
+	data, err := ioutil.ReadFile(store.filename("wolfeidau"))
+	require.Nil(t, err)
+	assert.False(t, bytes.Contains(data, []byte("mark")), "cookies should be encrypted")
+
+	info, err := os.Stat(store.filename("wolfeidau"))
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := store.Load("wolfeidau")
+	require.Nil(t, err)
+	assert.Equal(t, entry, loaded)
+
+	// a jar saved for one idp account can't be passed off as another
+	require.Nil(t, os.Rename(store.filename("wolfeidau"), store.filename("other")))
+	_, err = store.Load("other")
+	assert.NotNil(t, err)
+
+	require.Nil(t, store.Delete("other"))
+	require.Nil(t, store.Delete("other"))
+}
+
+func TestFileStoreKeyConcurrently(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cookiecache")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	entry := &Entry{URL: "https://id.example.com", Username: "mark", Cookies: []byte(`{"Entries":{}}`)}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			store := &FileStore{Dir: dir}
+			assert.Nil(t, store.Save(fmt.Sprintf("account-%d", i), entry))
+		}(i)
+	}
+	wg.Wait()
+
+	// every jar is readable with the one key left on disk
+	store := &FileStore{Dir: dir}
+	for i := 0; i < 10; i++ {
+		_, err := store.Load(fmt.Sprintf("account-%d", i))
+		assert.Nil(t, err)
+	}
+}
+
+func TestEntryValid(t *testing.T) {
+	entry := &Entry{URL: "https://id.example.com", Username: "mark", Cookies: []byte(`{"Entries":{}}`)}
+
+	assert.True(t, entry.Valid("https://id.example.com", "mark"))
+	assert.True(t, entry.Valid("https://id.example.com", ""))
+	assert.False(t, entry.Valid("https://id.example.com", "other"))
+	assert.False(t, entry.Valid("https://id.other.com", "mark"))
+	assert.False(t, (&Entry{URL: "https://id.example.com"}).Valid("https://id.example.com", ""))
+}
+
+func TestNewStore(t *testing.T) {
+	store, err := NewStore("")
+	require.Nil(t, err)
+	assert.Nil(t, store)
+
+	store, err = NewStore(ModeKeychain)
+	require.Nil(t, err)
+	assert.IsType(t, &KeychainStore{}, store)
+
+	_, err = NewStore("s3")
+	assert.NotNil(t, err)
+}
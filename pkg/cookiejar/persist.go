@@ -0,0 +1,79 @@
+package cookiejar
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+)
+
+// persistedJar the serialized form of a jar, entries keyed by their eTLD+1
+type persistedJar struct {
+	Entries map[string][]entry
+}
+
+// Save write the cookies which have not expired to w as JSON, session cookies are included as the IdP session
+// they carry is the reason to persist the jar
+func (j *Jar) Save(w io.Writer) error {
+	return j.save(w, time.Now())
+}
+
+func (j *Jar) save(w io.Writer, now time.Time) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	persisted := persistedJar{Entries: make(map[string][]entry)}
+
+	for key, submap := range j.entries {
+		for _, e := range submap {
+			if e.Persistent && !e.Expires.After(now) {
+				continue
+			}
+			persisted.Entries[key] = append(persisted.Entries[key], e)
+		}
+	}
+
+	// keep the order cookies were set in, so they are sent in the same order once loaded
+	for _, entries := range persisted.Entries {
+		sort.Slice(entries, func(a, b int) bool { return entries[a].seqNum < entries[b].seqNum })
+	}
+
+	return json.NewEncoder(w).Encode(&persisted)
+}
+
+// Load add the cookies written by Save to the jar, skipping those which have expired since
+func (j *Jar) Load(r io.Reader) error {
+	return j.load(r, time.Now())
+}
+
+func (j *Jar) load(r io.Reader, now time.Time) error {
+	var persisted persistedJar
+
+	err := json.NewDecoder(r).Decode(&persisted)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for key, entries := range persisted.Entries {
+		for _, e := range entries {
+			if e.Persistent && !e.Expires.After(now) {
+				continue
+			}
+
+			submap := j.entries[key]
+			if submap == nil {
+				submap = make(map[string]entry)
+				j.entries[key] = submap
+			}
+
+			e.seqNum = j.nextSeqNum
+			j.nextSeqNum++
+			submap[e.id()] = e
+		}
+	}
+
+	return nil
+}
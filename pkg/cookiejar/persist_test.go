@@ -0,0 +1,40 @@
+package cookiejar
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSaveLoad(t *testing.T) {
+	jar := newTestJar()
+
+	u, _ := url.Parse("https://id.example.com/idp/")
+	jar.setCookies(u, []*http.Cookie{
+		{Name: "session", Value: "abc"},
+		{Name: "remember", Value: "def", MaxAge: 3600},
+		{Name: "shortlived", Value: "ghi", MaxAge: 60},
+	}, tNow)
+
+	buf := new(bytes.Buffer)
+	if err := jar.save(buf, tNow); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := newTestJar()
+	if err := loaded.load(buf, tNow.Add(10*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	got := loaded.cookies(u, tNow.Add(10*time.Minute))
+	if len(got) != 2 || got[0].String() != "session=abc" || got[1].String() != "remember=def" {
+		t.Errorf("got cookies %v, want session=abc remember=def", got)
+	}
+
+	other, _ := url.Parse("https://www.example.org/")
+	if got := loaded.cookies(other, tNow); len(got) != 0 {
+		t.Errorf("got cookies %v for another domain", got)
+	}
+}
@@ -0,0 +1,181 @@
+package credserver
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/versent/saml2aws/pkg/awsconfig"
+)
+
+const (
+	// RefreshMargin credentials are refreshed this long before they expire
+	RefreshMargin = 5 * time.Minute
+
+	// RetryInterval the wait before trying again after a failed refresh
+	RetryInterval = time.Minute
+
+	// UnixPrefix marks an address as the path of a unix socket
+	UnixPrefix = "unix:"
+)
+
+var logger = logrus.WithField("pkg", "credserver")
+
+// Provider fetch a fresh set of credentials, logging in to the IdP as needed
+type Provider func() (*awsconfig.AWSCredentials, error)
+
+// containerCredentials the document returned by the ECS container credentials endpoint
+type containerCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string
+	Token           string
+	Expiration      string
+}
+
+// Server answers the ECS container credentials protocol with credentials which are refreshed before they expire
+type Server struct {
+	// Token the value clients must send in the Authorization header
+	Token string
+
+	provider Provider
+	now      func() time.Time
+
+	mu    sync.Mutex
+	creds *awsconfig.AWSCredentials
+}
+
+// New create a server handing out the credentials from the provider, guarded by a random authorization token
+func New(provider Provider) (*Server, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, errors.Wrap(err, "failed to generate authorization token")
+	}
+
+	return &Server{
+		Token:    hex.EncodeToString(token),
+		provider: provider,
+		now:      time.Now,
+	}, nil
+}
+
+// Credentials return the current credentials, refreshing them if they are about to expire
+func (s *Server) Credentials() (*awsconfig.AWSCredentials, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.creds != nil && s.now().Add(RefreshMargin).Before(s.creds.Expires) {
+		return s.creds, nil
+	}
+
+	creds, err := s.provider()
+	if err != nil {
+		return nil, err
+	}
+
+	s.creds = creds
+
+	return creds, nil
+}
+
+// RefreshLoop refresh the credentials ahead of their expiry until stop is closed, so clients never wait on a login
+func (s *Server) RefreshLoop(stop <-chan struct{}) {
+	for {
+		wait := RetryInterval
+
+		creds, err := s.Credentials()
+		if err != nil {
+			logger.WithError(err).Error("unable to refresh credentials")
+		} else {
+			wait = creds.Expires.Sub(s.now()) - RefreshMargin
+			if wait < RetryInterval {
+				wait = RetryInterval
+			}
+			logger.WithField("expires", creds.Expires).Debug("credentials refreshed")
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// ServeHTTP return the credentials to clients presenting the authorization token
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), []byte(s.Token)) != 1 {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	creds, err := s.Credentials()
+	if err != nil {
+		logger.WithError(err).Error("unable to fetch credentials")
+		http.Error(w, "unable to fetch credentials", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	err = json.NewEncoder(w).Encode(&containerCredentials{
+		AccessKeyID:     creds.AWSAccessKey,
+		SecretAccessKey: creds.AWSSecretKey,
+		Token:           creds.AWSSessionToken,
+		Expiration:      creds.Expires.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		logger.WithError(err).Debug("unable to write credentials")
+	}
+}
+
+// Listen listen on a loopback address, or on a unix socket when the address starts with unix:
+func Listen(address string) (net.Listener, error) {
+	if strings.HasPrefix(address, UnixPrefix) {
+		return net.Listen("unix", strings.TrimPrefix(address, UnixPrefix))
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid listen address")
+	}
+
+	if host != "localhost" {
+		ip := net.ParseIP(host)
+		if ip == nil || !ip.IsLoopback() {
+			return nil, errors.Errorf("refusing to serve credentials on %s, use a loopback address", host)
+		}
+	}
+
+	return net.Listen("tcp", address)
+}
+
+// EnvVars build the env vars pointing the AWS SDKs at the server, the static keys are cleared as they would take
+// precedence over the server
+func (s *Server) EnvVars(listener net.Listener) ([]string, error) {
+	if listener.Addr().Network() != "tcp" {
+		return nil, errors.New("the AWS SDKs can only reach the server over tcp")
+	}
+
+	return []string{
+		fmt.Sprintf("AWS_CONTAINER_CREDENTIALS_FULL_URI=http://%s/", listener.Addr().String()),
+		fmt.Sprintf("AWS_CONTAINER_AUTHORIZATION_TOKEN=%s", s.Token),
+		"AWS_ACCESS_KEY_ID=",
+		"AWS_SECRET_ACCESS_KEY=",
+		"AWS_SESSION_TOKEN=",
+		"AWS_SECURITY_TOKEN=",
+	}, nil
+}
@@ -0,0 +1,83 @@
+package credserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/versent/saml2aws/pkg/awsconfig"
+)
+
+func TestServeHTTP(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	logins := 0
+
+	s, err := New(func() (*awsconfig.AWSCredentials, error) {
+		logins++
+		return &awsconfig.AWSCredentials{
+			AWSAccessKey:    "AKIA",
+			AWSSecretKey:    "secret",
+			AWSSessionToken: "token",
+			Expires:         now.Add(time.Hour),
+		}, nil
+	})
+	require.Nil(t, err)
+	s.now = func() time.Time { return now }
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL)
+	require.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	assert.Equal(t, 0, logins)
+
+	req, err := http.NewRequest("GET", ts.URL, nil)
+	require.Nil(t, err)
+	req.Header.Set("Authorization", s.Token)
+
+	res, err = http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var creds map[string]string
+	require.Nil(t, json.NewDecoder(res.Body).Decode(&creds))
+	assert.Equal(t, map[string]string{
+		"AccessKeyId":     "AKIA",
+		"SecretAccessKey": "secret",
+		"Token":           "token",
+		"Expiration":      "2020-01-01T01:00:00Z",
+	}, creds)
+
+	// valid credentials are reused, those about to expire are refreshed
+	_, err = s.Credentials()
+	require.Nil(t, err)
+	assert.Equal(t, 1, logins)
+
+	now = now.Add(time.Hour - time.Minute)
+	_, err = s.Credentials()
+	require.Nil(t, err)
+	assert.Equal(t, 2, logins)
+}
+
+func TestListen(t *testing.T) {
+	_, err := Listen("0.0.0.0:0")
+	assert.NotNil(t, err)
+
+	l, err := Listen("127.0.0.1:0")
+	require.Nil(t, err)
+	defer l.Close()
+
+	s := &Server{Token: "abc"}
+
+	env, err := s.EnvVars(l)
+	require.Nil(t, err)
+	assert.Equal(t, "AWS_CONTAINER_CREDENTIALS_FULL_URI=http://"+l.Addr().String()+"/", env[0])
+	assert.Equal(t, "AWS_CONTAINER_AUTHORIZATION_TOKEN=abc", env[1])
+}
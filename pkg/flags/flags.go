@@ -6,26 +6,30 @@ import (
 
 // CommonFlags flags common to all of the `saml2aws` commands (except `help`)
 type CommonFlags struct {
-	AppID                string
-	ClientID             string
-	ClientSecret         string
-	ConfigFile           string
-	IdpAccount           string
-	IdpProvider          string
-	MFA                  string
-	MFAToken             string
-	URL                  string
-	Username             string
-	Password             string
-	RoleArn              string
-	AmazonWebservicesURN string
-	SessionDuration      int
-	SkipPrompt           bool
-	SkipVerify           bool
-	Profile              string
-	Subdomain            string
-	ResourceID           string
-	DisableKeychain      bool
+	AppID                  string
+	ClientID               string
+	ClientSecret           string
+	ConfigFile             string
+	IdpAccount             string
+	IdpProvider            string
+	MFA                    string
+	MFAToken               string
+	URL                    string
+	Username               string
+	Password               string
+	RoleArn                string
+	AmazonWebservicesURN   string
+	SessionDuration        int
+	SessionDurationAuto    bool
+	SessionDurationLadder  string
+	SkipPrompt             bool
+	SkipVerify             bool
+	Profile                string
+	Subdomain              string
+	ResourceID             string
+	DisableKeychain        bool
+	DisableAssertionCache  bool
+	DisableCredentialCache bool
 }
 
 // LoginExecFlags flags for the Login / Exec commands
@@ -34,6 +38,39 @@ type LoginExecFlags struct {
 	Force        bool
 	DuoMFAOption string
 	ExecProfile  string
+	Roles        []string
+	WithServer   bool
+	Direct       bool
+	All          bool
+}
+
+// ConsoleFlags flags for the `console` command
+type ConsoleFlags struct {
+	LoginExecFlags *LoginExecFlags
+	Destination    string
+	Issuer         string
+	FederationURL  string
+	Link           bool
+}
+
+// StatusFlags flags for the `status` command
+type StatusFlags struct {
+	CommonFlags *CommonFlags
+	IdpAccounts []string
+	Format      string
+	Verify      bool
+}
+
+// ServeFlags flags for the `serve` command
+type ServeFlags struct {
+	LoginExecFlags *LoginExecFlags
+	Address        string
+}
+
+// LogoutFlags flags for the `logout` command
+type LogoutFlags struct {
+	LoginExecFlags *LoginExecFlags
+	SLO            bool
 }
 
 // ApplyFlagOverrides overrides IDPAccount with command line settings
@@ -68,6 +105,16 @@ func ApplyFlagOverrides(commonFlags *CommonFlags, account *cfg.IDPAccount) {
 
 	if commonFlags.SessionDuration != 0 {
 		account.SessionDuration = commonFlags.SessionDuration
+		// an explicit duration replaces auto from the configuration
+		account.SessionDurationAuto = false
+	}
+
+	if commonFlags.SessionDurationAuto {
+		account.SessionDurationAuto = commonFlags.SessionDurationAuto
+	}
+
+	if commonFlags.SessionDurationLadder != "" {
+		account.SessionDurationLadder = commonFlags.SessionDurationLadder
 	}
 
 	if commonFlags.Profile != "" {
@@ -84,4 +131,6 @@ func ApplyFlagOverrides(commonFlags *CommonFlags, account *cfg.IDPAccount) {
 	if commonFlags.ResourceID != "" {
 		account.ResourceID = commonFlags.ResourceID
 	}
+
+	account.ApplyPartitionURN()
 }
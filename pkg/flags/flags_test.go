@@ -10,14 +10,16 @@ import (
 func TestOverrideAllFlags(t *testing.T) {
 
 	commonFlags := &CommonFlags{
-		IdpProvider:          "ADFS",
-		MFA:                  "mymfa",
-		SkipVerify:           true,
-		URL:                  "https://id.example.com",
-		Username:             "myuser",
-		AmazonWebservicesURN: "urn:amazon:webservices",
-		SessionDuration:      3600,
-		Profile:              "saml",
+		IdpProvider:           "ADFS",
+		MFA:                   "mymfa",
+		SkipVerify:            true,
+		URL:                   "https://id.example.com",
+		Username:              "myuser",
+		AmazonWebservicesURN:  "urn:amazon:webservices",
+		SessionDuration:       3600,
+		SessionDurationAuto:   true,
+		SessionDurationLadder: "8h,1h",
+		Profile:               "saml",
 	}
 	idpa := &cfg.IDPAccount{
 		Provider:             "Ping",
@@ -29,14 +31,16 @@ func TestOverrideAllFlags(t *testing.T) {
 	}
 
 	expected := &cfg.IDPAccount{
-		Provider:             "ADFS",
-		MFA:                  "mymfa",
-		SkipVerify:           true,
-		URL:                  "https://id.example.com",
-		Username:             "myuser",
-		AmazonWebservicesURN: "urn:amazon:webservices",
-		SessionDuration:      3600,
-		Profile:              "saml",
+		Provider:              "ADFS",
+		MFA:                   "mymfa",
+		SkipVerify:            true,
+		URL:                   "https://id.example.com",
+		Username:              "myuser",
+		AmazonWebservicesURN:  "urn:amazon:webservices",
+		SessionDuration:       3600,
+		SessionDurationAuto:   true,
+		SessionDurationLadder: "8h,1h",
+		Profile:               "saml",
 	}
 	ApplyFlagOverrides(commonFlags, idpa)
 
@@ -74,3 +78,38 @@ func TestNoOverrides(t *testing.T) {
 
 	assert.Equal(t, expected, idpa)
 }
+
+func TestSessionDurationOverridesAuto(t *testing.T) {
+
+	commonFlags := &CommonFlags{
+		SessionDuration: 7200,
+	}
+	idpa := &cfg.IDPAccount{
+		SessionDuration:     3600,
+		SessionDurationAuto: true,
+	}
+
+	ApplyFlagOverrides(commonFlags, idpa)
+
+	assert.Equal(t, 7200, idpa.SessionDuration)
+	assert.False(t, idpa.SessionDurationAuto)
+}
+
+func TestPartitionURN(t *testing.T) {
+
+	commonFlags := &CommonFlags{
+		RoleArn: "arn:aws-us-gov:iam::000000000001:role/Development",
+	}
+	idpa := cfg.NewIDPAccount()
+
+	ApplyFlagOverrides(commonFlags, idpa)
+
+	assert.Equal(t, "urn:amazon:webservices:govcloud", idpa.AmazonWebservicesURN)
+
+	commonFlags.AmazonWebservicesURN = "urn:example:webservices"
+	idpa = cfg.NewIDPAccount()
+
+	ApplyFlagOverrides(commonFlags, idpa)
+
+	assert.Equal(t, "urn:example:webservices", idpa.AmazonWebservicesURN)
+}
@@ -0,0 +1,93 @@
+package flock
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// pollInterval how often a waiting process checks if the lock has been released
+const pollInterval = 100 * time.Millisecond
+
+// ErrTimeout returned when the lock is still held by another process once the timeout has passed
+var ErrTimeout = errors.New("timed out waiting for lock")
+
+// Lock an advisory lock held on a file, shared by every saml2aws process on the machine
+type Lock struct {
+	file *os.File
+}
+
+// Acquire block until the lock on the file is held, the file is created if needed
+func Acquire(filename string) (*Lock, error) {
+	file, err := openLockFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	err = lockFile(file)
+	if err != nil {
+		file.Close()
+		return nil, errors.Wrapf(err, "unable to lock %s", filename)
+	}
+
+	return &Lock{file: file}, nil
+}
+
+// AcquireTimeout wait up to the timeout for the lock on the file, calling waiting once if another process holds it
+func AcquireTimeout(filename string, timeout time.Duration, waiting func()) (*Lock, error) {
+	file, err := openLockFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for i := 0; ; i++ {
+		ok, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, errors.Wrapf(err, "unable to lock %s", filename)
+		}
+		if ok {
+			return &Lock{file: file}, nil
+		}
+
+		if time.Now().After(deadline) {
+			file.Close()
+			return nil, ErrTimeout
+		}
+
+		if i == 0 && waiting != nil {
+			waiting()
+		}
+
+		time.Sleep(pollInterval)
+	}
+}
+
+func openLockFile(filename string) (*os.File, error) {
+	err := os.MkdirAll(filepath.Dir(filename), 0700)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to create %s directory", filepath.Dir(filename))
+	}
+
+	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open lock file %s", filename)
+	}
+
+	return file, nil
+}
+
+// Release release the lock, the lock file is left in place as removing it would race with other processes
+func (l *Lock) Release() error {
+	err := unlockFile(l.file)
+	if err != nil {
+		l.file.Close()
+		return err
+	}
+
+	return l.file.Close()
+}
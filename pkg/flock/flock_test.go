@@ -0,0 +1,76 @@
+package flock
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquire(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flock")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	lockFilename := filepath.Join(dir, "nested", "counter.lock")
+	counterFilename := filepath.Join(dir, "counter")
+	require.Nil(t, ioutil.WriteFile(counterFilename, []byte("0"), 0600))
+
+	// every goroutine increments the counter, without the lock some of the increments would be lost
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			lock, err := Acquire(lockFilename)
+			require.Nil(t, err)
+			defer lock.Release()
+
+			data, err := ioutil.ReadFile(counterFilename)
+			require.Nil(t, err)
+			n, err := strconv.Atoi(string(data))
+			require.Nil(t, err)
+
+			time.Sleep(time.Millisecond)
+
+			require.Nil(t, ioutil.WriteFile(counterFilename, []byte(strconv.Itoa(n+1)), 0600))
+		}()
+	}
+	wg.Wait()
+
+	data, err := ioutil.ReadFile(counterFilename)
+	require.Nil(t, err)
+	assert.Equal(t, "20", string(data))
+}
+
+func TestAcquireTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flock")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "login.lock")
+
+	lock, err := Acquire(filename)
+	require.Nil(t, err)
+
+	waited := 0
+	_, err = AcquireTimeout(filename, 200*time.Millisecond, func() { waited++ })
+	assert.Equal(t, ErrTimeout, err)
+	assert.Equal(t, 1, waited)
+
+	go func(held *Lock) {
+		time.Sleep(200 * time.Millisecond)
+		held.Release()
+	}(lock)
+
+	lock, err = AcquireTimeout(filename, 5*time.Second, nil)
+	require.Nil(t, err)
+	require.Nil(t, lock.Release())
+}
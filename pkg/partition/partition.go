@@ -0,0 +1,136 @@
+package partition
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Partition the endpoints and defaults which differ between the isolated AWS partitions
+type Partition struct {
+	ID            string
+	SignInURL     string
+	FederationURL string
+	ConsoleURL    string
+	URN           string
+	Region        string
+	regionPrefix  string
+	domain        string
+}
+
+var (
+	// AWS the commercial partition
+	AWS = &Partition{
+		ID:            "aws",
+		SignInURL:     "https://signin.aws.amazon.com/saml",
+		FederationURL: "https://signin.aws.amazon.com/federation",
+		ConsoleURL:    "https://console.aws.amazon.com/",
+		URN:           "urn:amazon:webservices",
+		Region:        "us-east-1",
+		domain:        "amazonaws.com",
+	}
+
+	// AWSCN the China partition
+	AWSCN = &Partition{
+		ID:            "aws-cn",
+		SignInURL:     "https://signin.amazonaws.cn/saml",
+		FederationURL: "https://signin.amazonaws.cn/federation",
+		ConsoleURL:    "https://console.amazonaws.cn/",
+		URN:           "urn:amazon:webservices:cn",
+		Region:        "cn-north-1",
+		regionPrefix:  "cn-",
+		domain:        "amazonaws.com.cn",
+	}
+
+	// AWSUSGov the GovCloud (US) partition
+	AWSUSGov = &Partition{
+		ID:            "aws-us-gov",
+		SignInURL:     "https://signin.amazonaws-us-gov.com/saml",
+		FederationURL: "https://signin.amazonaws-us-gov.com/federation",
+		ConsoleURL:    "https://console.amazonaws-us-gov.com/",
+		URN:           "urn:amazon:webservices:govcloud",
+		Region:        "us-gov-west-1",
+		regionPrefix:  "us-gov-",
+		domain:        "amazonaws.com",
+	}
+
+	partitions = []*Partition{AWS, AWSCN, AWSUSGov}
+)
+
+// ByID return the partition with the given id, for example aws-us-gov
+func ByID(id string) (*Partition, error) {
+	for _, p := range partitions {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+
+	return nil, errors.Errorf("unknown aws partition %s", id)
+}
+
+// FromARN return the partition named in an ARN such as arn:aws-cn:iam::123456789012:role/admin
+func FromARN(arn string) (*Partition, error) {
+	tokens := strings.SplitN(arn, ":", 3)
+	if len(tokens) < 3 || tokens[0] != "arn" {
+		return nil, errors.Errorf("invalid arn %s", arn)
+	}
+
+	return ByID(tokens[1])
+}
+
+// FromRoleARNs return the partition shared by the role and principal ARNs
+func FromRoleARNs(roleARN, principalARN string) (*Partition, error) {
+	p, err := FromARN(roleARN)
+	if err != nil {
+		return nil, err
+	}
+
+	if principalARN == "" {
+		return p, nil
+	}
+
+	principalPartition, err := FromARN(principalARN)
+	if err != nil {
+		return nil, err
+	}
+
+	if principalPartition != p {
+		return nil, errors.Errorf("role %s and principal %s are in different partitions", roleARN, principalARN)
+	}
+
+	return p, nil
+}
+
+// HasRegion returns true if the region is part of the partition
+func (p *Partition) HasRegion(region string) bool {
+	for _, other := range partitions {
+		if other != p && other.regionPrefix != "" && strings.HasPrefix(region, other.regionPrefix) {
+			return false
+		}
+	}
+
+	return region != "" && strings.HasPrefix(region, p.regionPrefix)
+}
+
+// ResolveRegion return the region configured in the environment if it is part of the partition, otherwise
+// the default region of the partition
+func (p *Partition) ResolveRegion() string {
+	for _, name := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region := os.Getenv(name); p.HasRegion(region) {
+			return region
+		}
+	}
+
+	return p.Region
+}
+
+// STSEndpoint return the regional STS endpoint in the partition
+func (p *Partition) STSEndpoint(region string) string {
+	return fmt.Sprintf("https://sts.%s.%s", region, p.domain)
+}
+
+func (p *Partition) String() string {
+	return p.ID
+}
@@ -0,0 +1,77 @@
+package partition
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromARN(t *testing.T) {
+	p, err := FromARN("arn:aws:iam::000000000001:role/Development")
+	require.Nil(t, err)
+	assert.Equal(t, AWS, p)
+
+	p, err = FromARN("arn:aws-cn:iam::000000000001:role/Development")
+	require.Nil(t, err)
+	assert.Equal(t, AWSCN, p)
+
+	p, err = FromARN("arn:aws-us-gov:iam::000000000001:saml-provider/ADFS")
+	require.Nil(t, err)
+	assert.Equal(t, AWSUSGov, p)
+	assert.Equal(t, "urn:amazon:webservices:govcloud", p.URN)
+
+	_, err = FromARN("arn:aws-iso:iam::000000000001:role/Development")
+	assert.Error(t, err)
+
+	_, err = FromARN("Development")
+	assert.Error(t, err)
+}
+
+func TestFromRoleARNs(t *testing.T) {
+	p, err := FromRoleARNs("arn:aws-us-gov:iam::000000000001:role/Development", "arn:aws-us-gov:iam::000000000001:saml-provider/ADFS")
+	require.Nil(t, err)
+	assert.Equal(t, AWSUSGov, p)
+
+	_, err = FromRoleARNs("arn:aws-us-gov:iam::000000000001:role/Development", "arn:aws:iam::000000000001:saml-provider/ADFS")
+	assert.Error(t, err)
+}
+
+func TestHasRegion(t *testing.T) {
+	assert.True(t, AWS.HasRegion("ap-southeast-2"))
+	assert.False(t, AWS.HasRegion("us-gov-east-1"))
+	assert.False(t, AWS.HasRegion("cn-northwest-1"))
+	assert.False(t, AWS.HasRegion(""))
+	assert.True(t, AWSUSGov.HasRegion("us-gov-east-1"))
+	assert.False(t, AWSUSGov.HasRegion("us-east-1"))
+	assert.True(t, AWSCN.HasRegion("cn-northwest-1"))
+}
+
+func TestResolveRegion(t *testing.T) {
+	for _, name := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		previous, ok := os.LookupEnv(name)
+		if ok {
+			defer os.Setenv(name, previous)
+		} else {
+			defer os.Unsetenv(name)
+		}
+		os.Unsetenv(name)
+	}
+
+	assert.Equal(t, "us-gov-west-1", AWSUSGov.ResolveRegion())
+
+	os.Setenv("AWS_DEFAULT_REGION", "ap-southeast-2")
+	assert.Equal(t, "ap-southeast-2", AWS.ResolveRegion())
+	assert.Equal(t, "us-gov-west-1", AWSUSGov.ResolveRegion())
+
+	os.Setenv("AWS_REGION", "us-gov-east-1")
+	assert.Equal(t, "ap-southeast-2", AWS.ResolveRegion())
+	assert.Equal(t, "us-gov-east-1", AWSUSGov.ResolveRegion())
+}
+
+func TestSTSEndpoint(t *testing.T) {
+	assert.Equal(t, "https://sts.us-east-1.amazonaws.com", AWS.STSEndpoint("us-east-1"))
+	assert.Equal(t, "https://sts.cn-north-1.amazonaws.com.cn", AWSCN.STSEndpoint("cn-north-1"))
+	assert.Equal(t, "https://sts.us-gov-west-1.amazonaws.com", AWSUSGov.STSEndpoint("us-gov-west-1"))
+}
@@ -1,7 +1,15 @@
 package prompter
 
+import "sync"
+
 var defaultPrompter Prompter = NewCli()
 
+// mu serialises the prompts so concurrent logins never interleave their questions
+var mu sync.Mutex
+
+// session serialises the interactive exchanges, such as a login, so each asks all of its questions back to back
+var session sync.Mutex
+
 // Prompter handles prompting user for input
 type Prompter interface {
 	RequestSecurityCode(string) string
@@ -14,35 +22,63 @@ type Prompter interface {
 
 // SetPrompter configure an aternate prompter to the default one
 func SetPrompter(prmpt Prompter) {
+	mu.Lock()
+	defer mu.Unlock()
+
 	defaultPrompter = prmpt
 }
 
+// BeginSession hold the prompter for an interactive exchange, other exchanges wait until the returned func ends it
+func BeginSession() func() {
+	session.Lock()
+
+	return session.Unlock
+}
+
 // RequestSecurityCode request a security code to be entered by the user
 func RequestSecurityCode(pattern string) string {
+	mu.Lock()
+	defer mu.Unlock()
+
 	return defaultPrompter.RequestSecurityCode(pattern)
 }
 
 // ChooseWithDefault given the choice return the option selected with a default
 func ChooseWithDefault(pr string, defaultValue string, options []string) (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
 	return defaultPrompter.ChooseWithDefault(pr, defaultValue, options)
 }
 
 // Choose given the choice return the option selected
 func Choose(pr string, options []string) int {
+	mu.Lock()
+	defer mu.Unlock()
+
 	return defaultPrompter.Choose(pr, options)
 }
 
 // StringRequired prompt for string which is required
 func StringRequired(pr string) string {
+	mu.Lock()
+	defer mu.Unlock()
+
 	return defaultPrompter.StringRequired(pr)
 }
 
 // String prompt for string which is required
 func String(pr string, defaultValue string) string {
+	mu.Lock()
+	defer mu.Unlock()
+
 	return defaultPrompter.String(pr, defaultValue)
 }
 
 // Password prompt for password which is required
 func Password(pr string) string {
+	mu.Lock()
+	defer mu.Unlock()
+
 	return defaultPrompter.Password(pr)
 }
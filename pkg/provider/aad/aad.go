@@ -611,12 +611,18 @@ type processAuthResponse struct {
 // New create a new AzureAD client
 func New(idpAccount *cfg.IDPAccount) (*Client, error) {
 
+	tlsConfig, err := provider.NewTLSConfig(idpAccount)
+	if err != nil {
+		return nil, errors.Wrap(err, "error building tls config")
+	}
+	tlsConfig.Renegotiation = tls.RenegotiateFreelyAsClient
+
 	tr := &http.Transport{
 		Proxy:           http.ProxyFromEnvironment,
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: idpAccount.SkipVerify, Renegotiation: tls.RenegotiateFreelyAsClient},
+		TLSClientConfig: tlsConfig,
 	}
 
-	client, err := provider.NewHTTPClient(tr)
+	client, err := provider.NewHTTPClient(tr, provider.BuildHTTPClientOpts(idpAccount))
 	if err != nil {
 		return nil, errors.Wrap(err, "error building http client")
 	}
@@ -627,6 +633,16 @@ func New(idpAccount *cfg.IDPAccount) (*Client, error) {
 	}, nil
 }
 
+// ResumeSession return the SAML response while the AzureAD session in the cookies is live
+func (ac *Client) ResumeSession(loginDetails *creds.LoginDetails) (string, error) {
+	return provider.ResumeSession(ac.client, ac.startURL())
+}
+
+// startURL the page starting the linked sign in to the AWS application
+func (ac *Client) startURL() string {
+	return fmt.Sprintf("%s/applications/redirecttofederatedapplication.aspx?Operation=LinkedSignIn&applicationId=%s", ac.idpAccount.URL, ac.idpAccount.AppID)
+}
+
 // Authenticate to AzureAD and return the data from the body of the SAML assertion.
 func (ac *Client) Authenticate(loginDetails *creds.LoginDetails) (string, error) {
 
@@ -636,9 +652,7 @@ func (ac *Client) Authenticate(loginDetails *creds.LoginDetails) (string, error)
 	// idpAccount.URL = https://account.activedirectory.windowsazure.com
 
 	// startSAML
-	startURL := fmt.Sprintf("%s/applications/redirecttofederatedapplication.aspx?Operation=LinkedSignIn&applicationId=%s", ac.idpAccount.URL, ac.idpAccount.AppID)
-
-	res, err := ac.client.Get(startURL)
+	res, err := ac.client.Get(ac.startURL())
 	if err != nil {
 		return samlAssertion, errors.Wrap(err, "error retrieving form")
 	}
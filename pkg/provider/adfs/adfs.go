@@ -28,12 +28,18 @@ type Client struct {
 // New create a new ADFS client
 func New(idpAccount *cfg.IDPAccount) (*Client, error) {
 
+	tlsConfig, err := provider.NewTLSConfig(idpAccount)
+	if err != nil {
+		return nil, errors.Wrap(err, "error building tls config")
+	}
+	tlsConfig.Renegotiation = tls.RenegotiateFreelyAsClient
+
 	tr := &http.Transport{
 		Proxy:           http.ProxyFromEnvironment,
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: idpAccount.SkipVerify, Renegotiation: tls.RenegotiateFreelyAsClient},
+		TLSClientConfig: tlsConfig,
 	}
 
-	client, err := provider.NewHTTPClient(tr)
+	client, err := provider.NewHTTPClient(tr, provider.BuildHTTPClientOpts(idpAccount))
 	if err != nil {
 		return nil, errors.Wrap(err, "error building http client")
 	}
@@ -50,11 +56,7 @@ func (ac *Client) Authenticate(loginDetails *creds.LoginDetails) (string, error)
 	var authSubmitURL string
 	var samlAssertion string
 
-	awsURN := url.QueryEscape(ac.idpAccount.AmazonWebservicesURN)
-
-	adfsURL := fmt.Sprintf("%s/adfs/ls/IdpInitiatedSignOn.aspx?loginToRp=%s", loginDetails.URL, awsURN)
-
-	res, err := ac.client.Get(adfsURL)
+	res, err := ac.client.Get(ac.signOnURL(loginDetails))
 	if err != nil {
 		return samlAssertion, errors.Wrap(err, "error retrieving form")
 	}
@@ -127,6 +129,18 @@ func (ac *Client) Authenticate(loginDetails *creds.LoginDetails) (string, error)
 	return samlAssertion, nil
 }
 
+// ResumeSession return the SAML response while the ADFS session in the cookies is live
+func (ac *Client) ResumeSession(loginDetails *creds.LoginDetails) (string, error) {
+	return provider.ResumeSession(ac.client, ac.signOnURL(loginDetails))
+}
+
+// signOnURL the IdP initiated sign on page for AWS
+func (ac *Client) signOnURL(loginDetails *creds.LoginDetails) string {
+	awsURN := url.QueryEscape(ac.idpAccount.AmazonWebservicesURN)
+
+	return fmt.Sprintf("%s/adfs/ls/IdpInitiatedSignOn.aspx?loginToRp=%s", loginDetails.URL, awsURN)
+}
+
 // vipMFA when supplied with the the form response document attempt to extract the VIP mfa related field
 // then use that to trigger a submit of the MFA security token
 func (ac *Client) vipMFA(authSubmitURL string, mfaToken string, res *http.Response) (*http.Response, error) {
@@ -10,9 +10,11 @@ import (
 
 	"github.com/Azure/go-ntlmssp"
 	"github.com/PuerkitoBio/goquery"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/versent/saml2aws/pkg/cfg"
 	"github.com/versent/saml2aws/pkg/creds"
+	"github.com/versent/saml2aws/pkg/provider"
 )
 
 var logger = logrus.WithField("provider", "adfs2")
@@ -25,10 +27,16 @@ type Client struct {
 
 // New new adfs2 client with ntlmssp configured
 func New(idpAccount *cfg.IDPAccount) (*Client, error) {
+	tlsConfig, err := provider.NewTLSConfig(idpAccount)
+	if err != nil {
+		return nil, errors.Wrap(err, "error building tls config")
+	}
+	tlsConfig.Renegotiation = tls.RenegotiateFreelyAsClient
+
 	transport := &ntlmssp.Negotiator{
 		RoundTripper: &http.Transport{
 			Proxy:           http.ProxyFromEnvironment,
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: idpAccount.SkipVerify, Renegotiation: tls.RenegotiateFreelyAsClient},
+			TLSClientConfig: tlsConfig,
 		},
 	}
 
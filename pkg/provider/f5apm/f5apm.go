@@ -33,8 +33,11 @@ type Client struct {
 // New create new F5 APM client
 func New(idpAccount *cfg.IDPAccount) (*Client, error) {
 
-	tr := provider.NewDefaultTransport(idpAccount.SkipVerify)
-	client, err := provider.NewHTTPClient(tr)
+	tr, err := provider.NewTransport(idpAccount)
+	if err != nil {
+		return nil, errors.Wrap(err, "error building http transport")
+	}
+	client, err := provider.NewHTTPClient(tr, provider.BuildHTTPClientOpts(idpAccount))
 	if err != nil {
 		return nil, errors.Wrap(err, "Error building HTTP client")
 	}
@@ -27,9 +27,12 @@ type Client struct {
 // New create a new Google Apps Client
 func New(idpAccount *cfg.IDPAccount) (*Client, error) {
 
-	tr := provider.NewDefaultTransport(idpAccount.SkipVerify)
+	tr, err := provider.NewTransport(idpAccount)
+	if err != nil {
+		return nil, errors.Wrap(err, "error building http transport")
+	}
 
-	client, err := provider.NewHTTPClient(tr)
+	client, err := provider.NewHTTPClient(tr, provider.BuildHTTPClientOpts(idpAccount))
 	if err != nil {
 		return nil, errors.Wrap(err, "error building http client")
 	}
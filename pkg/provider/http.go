@@ -1,15 +1,19 @@
 package provider
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/versent/saml2aws/pkg/cfg"
 	"github.com/versent/saml2aws/pkg/cookiejar"
 	"github.com/versent/saml2aws/pkg/dump"
 
@@ -23,6 +27,7 @@ import (
 type HTTPClient struct {
 	http.Client
 	CheckResponseStatus func(*http.Request, *http.Response) error
+	Options             *HTTPClientOptions
 }
 
 // NewDefaultTransport configure a transport with the TLS skip verify option
@@ -42,27 +47,95 @@ func NewDefaultTransport(skipVerify bool) *http.Transport {
 	}
 }
 
-// NewHTTPClient configure the default http client used by the providers
-func NewHTTPClient(tr http.RoundTripper) (*HTTPClient, error) {
+// HTTPClientOptions settings of the http clients built for an idp account
+type HTTPClientOptions struct {
+	CookieJar      *cookiejar.Jar // shared jar holding a restored IdP session, a new jar is used when nil
+	Timeout        time.Duration  // limit on a request including its retries, 0 for no limit
+	RequestTimeout time.Duration  // limit on each attempt of a request, 0 for no limit
+	MaxRetries     int            // retries of safe requests failing with transient errors
+	RetryWaitMin   time.Duration  // backoff before the first retry, doubled for each retry after it
+	RetryWaitMax   time.Duration  // cap on the backoff between retries
+}
+
+var (
+	cookieJarsMu sync.Mutex
+	cookieJars   = map[*cfg.IDPAccount]*cookiejar.Jar{}
+
+	// spinnerDisabled set to 1 while the spinner is disabled, read by the requests in flight
+	spinnerDisabled int32
+)
 
-	options := &cookiejar.Options{
+// NewCookieJar build an empty cookie jar using the public suffix list
+func NewCookieJar() (*cookiejar.Jar, error) {
+	return cookiejar.New(&cookiejar.Options{
 		PublicSuffixList: publicsuffix.List,
+	})
+}
+
+// SetCookieJar share the jar with the clients built for the idp account from now on so they resume the IdP session it
+// holds, nil goes back to a new jar per client
+func SetCookieJar(account *cfg.IDPAccount, jar *cookiejar.Jar) {
+	cookieJarsMu.Lock()
+	defer cookieJarsMu.Unlock()
+
+	if jar == nil {
+		delete(cookieJars, account)
+		return
 	}
 
-	jar, err := cookiejar.New(options)
-	if err != nil {
-		return nil, err
+	cookieJars[account] = jar
+}
+
+// SetSpinner enable or disable the spinner shown on a terminal while requests are in flight
+func SetSpinner(enabled bool) {
+	var disabled int32
+	if !enabled {
+		disabled = 1
 	}
 
-	client := http.Client{Transport: tr, Jar: jar}
+	atomic.StoreInt32(&spinnerDisabled, disabled)
+}
 
-	return &HTTPClient{client, nil}, nil
+// BuildHTTPClientOpts build the options of the http clients used by the provider of the idp account
+func BuildHTTPClientOpts(account *cfg.IDPAccount) *HTTPClientOptions {
+	cookieJarsMu.Lock()
+	defer cookieJarsMu.Unlock()
+
+	return &HTTPClientOptions{
+		CookieJar:      cookieJars[account],
+		Timeout:        time.Duration(account.Timeout) * time.Second,
+		RequestTimeout: time.Duration(account.RequestTimeout) * time.Second,
+		MaxRetries:     account.HTTPRetries,
+		RetryWaitMin:   DefaultRetryWaitMin,
+		RetryWaitMax:   DefaultRetryWaitMax,
+	}
+}
+
+// NewHTTPClient configure the default http client used by the providers
+func NewHTTPClient(tr http.RoundTripper, opts *HTTPClientOptions) (*HTTPClient, error) {
+
+	if opts == nil {
+		opts = &HTTPClientOptions{}
+	}
+
+	jar := opts.CookieJar
+	if jar == nil {
+		var err error
+		jar, err = NewCookieJar()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	client := http.Client{Transport: tr, Jar: jar, Timeout: opts.RequestTimeout}
+
+	return &HTTPClient{Client: client, Options: opts}, nil
 }
 
 // Do do the request
 func (hc *HTTPClient) Do(req *http.Request) (*http.Response, error) {
 
-	if isatty.IsTerminal(os.Stdout.Fd()) {
+	if atomic.LoadInt32(&spinnerDisabled) == 0 && isatty.IsTerminal(os.Stdout.Fd()) {
 		cs := spinner.CharSets[14]
 
 		// use a NON unicode spinner for windows
@@ -72,6 +145,7 @@ func (hc *HTTPClient) Do(req *http.Request) (*http.Response, error) {
 
 		if logrus.GetLevel() != logrus.DebugLevel {
 			s := spinner.New(cs, 100*time.Millisecond)
+			s.Writer = os.Stdout
 			defer func() {
 				s.Stop()
 			}()
@@ -81,13 +155,27 @@ func (hc *HTTPClient) Do(req *http.Request) (*http.Response, error) {
 
 	req.Header.Set("User-Agent", fmt.Sprintf("saml2aws/1.0 (%s %s) Versent", runtime.GOOS, runtime.GOARCH))
 
-	hc.logHTTPRequest(req)
+	opts := hc.Options
+	if opts == nil {
+		opts = &HTTPClientOptions{}
+	}
 
-	resp, err := hc.Client.Do(req)
+	cancel := func() {}
+	if opts.Timeout > 0 {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(req.Context(), opts.Timeout)
+		req = req.WithContext(ctx)
+	}
+
+	resp, err := hc.doWithRetries(req, opts)
 	if err != nil {
+		cancel()
 		return resp, err
 	}
 
+	// the timeout also covers reading the body
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+
 	// if a response check has been configured
 	if hc.CheckResponseStatus != nil {
 		err = hc.CheckResponseStatus(req, resp)
@@ -98,7 +186,7 @@ func (hc *HTTPClient) Do(req *http.Request) (*http.Response, error) {
 
 	hc.logHTTPResponse(resp)
 
-	return resp, err
+	return resp, nil
 }
 
 // DisableFollowRedirect disable redirects
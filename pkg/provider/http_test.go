@@ -1,9 +1,13 @@
 package provider
 
 import (
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -16,7 +20,7 @@ func TestClientDoGetOK(t *testing.T) {
 
 	rt := NewDefaultTransport(false)
 
-	hc, err := NewHTTPClient(rt)
+	hc, err := NewHTTPClient(rt, nil)
 	require.Nil(t, err)
 
 	// hc := &HTTPClient{Client: http.Client{}}
@@ -39,7 +43,7 @@ func TestClientDisableRedirect(t *testing.T) {
 
 	rt := NewDefaultTransport(false)
 
-	hc, err := NewHTTPClient(rt)
+	hc, err := NewHTTPClient(rt, nil)
 	require.Nil(t, err)
 
 	hc.DisableFollowRedirect()
@@ -70,3 +74,165 @@ func TestClientDoResponseCheck(t *testing.T) {
 	require.Error(t, err)
 	require.Equal(t, 400, res.StatusCode)
 }
+
+// flakyServer fail the first requests with the status, or by dropping the connection when the status is 0
+func flakyServer(t *testing.T, failures int32, status int) (*httptest.Server, *int32) {
+	var requests int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) > failures {
+			w.Write([]byte("OK"))
+			return
+		}
+
+		if status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		require.Nil(t, err)
+		conn.Close()
+	}))
+
+	return ts, &requests
+}
+
+func retryOpts() *HTTPClientOptions {
+	return &HTTPClientOptions{MaxRetries: 2, RetryWaitMin: time.Millisecond, RetryWaitMax: 5 * time.Millisecond}
+}
+
+func TestClientDoRetriesTransientStatus(t *testing.T) {
+	ts, requests := flakyServer(t, 2, http.StatusServiceUnavailable)
+	defer ts.Close()
+
+	hc, err := NewHTTPClient(NewDefaultTransport(false), retryOpts())
+	require.Nil(t, err)
+
+	req, err := http.NewRequest("GET", ts.URL, nil)
+	require.Nil(t, err)
+
+	res, err := hc.Do(req)
+	require.Nil(t, err)
+	require.Equal(t, 200, res.StatusCode)
+	require.Equal(t, int32(3), atomic.LoadInt32(requests))
+}
+
+func TestClientDoRetriesDroppedConnection(t *testing.T) {
+	ts, requests := flakyServer(t, 1, 0)
+	defer ts.Close()
+
+	hc, err := NewHTTPClient(NewDefaultTransport(false), retryOpts())
+	require.Nil(t, err)
+
+	req, err := http.NewRequest("GET", ts.URL, nil)
+	require.Nil(t, err)
+
+	res, err := hc.Do(req)
+	require.Nil(t, err)
+	require.Equal(t, 200, res.StatusCode)
+	require.Equal(t, int32(2), atomic.LoadInt32(requests))
+}
+
+func TestClientDoRetriesExhausted(t *testing.T) {
+	ts, requests := flakyServer(t, 5, http.StatusBadGateway)
+	defer ts.Close()
+
+	hc, err := NewHTTPClient(NewDefaultTransport(false), retryOpts())
+	require.Nil(t, err)
+
+	req, err := http.NewRequest("GET", ts.URL, nil)
+	require.Nil(t, err)
+
+	res, err := hc.Do(req)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusBadGateway, res.StatusCode)
+	require.Equal(t, int32(3), atomic.LoadInt32(requests))
+}
+
+func TestClientDoNoRetryUnsafeOrPermanent(t *testing.T) {
+	ts, requests := flakyServer(t, 1, http.StatusServiceUnavailable)
+	defer ts.Close()
+
+	hc, err := NewHTTPClient(NewDefaultTransport(false), retryOpts())
+	require.Nil(t, err)
+
+	req, err := http.NewRequest("POST", ts.URL, strings.NewReader("username=wolfeidau"))
+	require.Nil(t, err)
+
+	res, err := hc.Do(req)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	require.Equal(t, int32(1), atomic.LoadInt32(requests))
+
+	ts, requests = flakyServer(t, 1, http.StatusNotFound)
+	defer ts.Close()
+
+	req, err = http.NewRequest("GET", ts.URL, nil)
+	require.Nil(t, err)
+
+	res, err = hc.Do(req)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+	require.Equal(t, int32(1), atomic.LoadInt32(requests))
+}
+
+func TestClientDoTimeouts(t *testing.T) {
+	var requests int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}
+		w.Write([]byte("OK"))
+	}))
+	defer ts.Close()
+
+	// the slow first attempt times out and the retry succeeds
+	opts := retryOpts()
+	opts.RequestTimeout = 50 * time.Millisecond
+
+	hc, err := NewHTTPClient(NewDefaultTransport(false), opts)
+	require.Nil(t, err)
+
+	req, err := http.NewRequest("GET", ts.URL, nil)
+	require.Nil(t, err)
+
+	res, err := hc.Do(req)
+	require.Nil(t, err)
+	require.Equal(t, 200, res.StatusCode)
+
+	body, err := ioutil.ReadAll(res.Body)
+	require.Nil(t, err)
+	require.Nil(t, res.Body.Close())
+	require.Equal(t, "OK", string(body))
+
+	// the overall timeout stops the retries
+	atomic.StoreInt32(&requests, 0)
+
+	opts = retryOpts()
+	opts.Timeout = 50 * time.Millisecond
+
+	hc, err = NewHTTPClient(NewDefaultTransport(false), opts)
+	require.Nil(t, err)
+
+	req, err = http.NewRequest("GET", ts.URL, nil)
+	require.Nil(t, err)
+
+	start := time.Now()
+	_, err = hc.Do(req)
+	require.Error(t, err)
+	require.True(t, time.Since(start) < time.Second)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	opts := &HTTPClientOptions{RetryWaitMin: 100 * time.Millisecond, RetryWaitMax: time.Second}
+
+	for attempt, max := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		wait := retryBackoff(opts, attempt)
+		require.True(t, wait >= max/2 && wait <= max, "attempt %d waited %v", attempt, wait)
+	}
+}
@@ -29,9 +29,12 @@ type Client struct {
 // New create a new KeyCloakClient
 func New(idpAccount *cfg.IDPAccount) (*Client, error) {
 
-	tr := provider.NewDefaultTransport(idpAccount.SkipVerify)
+	tr, err := provider.NewTransport(idpAccount)
+	if err != nil {
+		return nil, errors.Wrap(err, "error building http transport")
+	}
 
-	client, err := provider.NewHTTPClient(tr)
+	client, err := provider.NewHTTPClient(tr, provider.BuildHTTPClientOpts(idpAccount))
 	if err != nil {
 		return nil, errors.Wrap(err, "error building http client")
 	}
@@ -93,6 +96,11 @@ func (kc *Client) Authenticate(loginDetails *creds.LoginDetails) (string, error)
 	return samlAssertion, nil
 }
 
+// ResumeSession return the SAML response while the KeyCloak session in the cookies is live
+func (kc *Client) ResumeSession(loginDetails *creds.LoginDetails) (string, error) {
+	return provider.ResumeSession(kc.client, loginDetails.URL)
+}
+
 func (kc *Client) getLoginForm(loginDetails *creds.LoginDetails) (string, url.Values, error) {
 
 	res, err := kc.client.Get(loginDetails.URL)
@@ -71,9 +71,12 @@ type VerifyRequest struct {
 // New creates a new Okta client
 func New(idpAccount *cfg.IDPAccount) (*Client, error) {
 
-	tr := provider.NewDefaultTransport(idpAccount.SkipVerify)
+	tr, err := provider.NewTransport(idpAccount)
+	if err != nil {
+		return nil, errors.Wrap(err, "error building http transport")
+	}
 
-	client, err := provider.NewHTTPClient(tr)
+	client, err := provider.NewHTTPClient(tr, provider.BuildHTTPClientOpts(idpAccount))
 	if err != nil {
 		return nil, errors.Wrap(err, "error building http client")
 	}
@@ -161,6 +164,11 @@ func (oc *Client) Authenticate(loginDetails *creds.LoginDetails) (string, error)
 	return oc.follow(ctx, req, loginDetails)
 }
 
+// ResumeSession return the SAML response of the app while the Okta session in the cookies is live
+func (oc *Client) ResumeSession(loginDetails *creds.LoginDetails) (string, error) {
+	return provider.ResumeSession(oc.client, loginDetails.URL)
+}
+
 func (oc *Client) follow(ctx context.Context, req *http.Request, loginDetails *creds.LoginDetails) (string, error) {
 
 	res, err := oc.client.Do(req)
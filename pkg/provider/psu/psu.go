@@ -55,7 +55,10 @@ type duoDevice struct {
 // New returns a new psu.Client with the browser and idp account instantiated
 func New(idpAccount *cfg.IDPAccount) (*Client, error) {
 
-	tr := provider.NewDefaultTransport(idpAccount.SkipVerify)
+	tr, err := provider.NewTransport(idpAccount)
+	if err != nil {
+		return nil, errors.Wrap(err, "error building http transport")
+	}
 
 	// create our browser
 	b := surf.NewBrowser()
@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultRetryWaitMin the backoff before the first retry of a request
+	DefaultRetryWaitMin = 500 * time.Millisecond
+
+	// DefaultRetryWaitMax the cap on the backoff between retries of a request
+	DefaultRetryWaitMax = 5 * time.Second
+)
+
+// cancelOnClose release the context of the request once its body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// doWithRetries send the request, retrying safe requests which fail with transient errors with an exponential backoff
+func (hc *HTTPClient) doWithRetries(req *http.Request, opts *HTTPClientOptions) (*http.Response, error) {
+	retries := 0
+	if isSafeMethod(req.Method) && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil) {
+		retries = opts.MaxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		hc.logHTTPRequest(req)
+
+		resp, err := hc.Client.Do(req)
+		if attempt >= retries || req.Context().Err() != nil || !isTransient(resp, err) {
+			return resp, err
+		}
+
+		wait := retryBackoff(opts, attempt)
+
+		logger := logrus.WithField("http", "client").WithFields(logrus.Fields{
+			"URL":     req.URL.String(),
+			"method":  req.Method,
+			"attempt": attempt + 1,
+			"wait":    wait,
+		})
+		if err != nil {
+			logger.WithError(err).Debug("HTTP retry")
+		} else {
+			logger.WithField("Status", resp.Status).Debug("HTTP retry")
+
+			// drain the body so the connection can be reused
+			io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// isSafeMethod report if the method is safe to repeat, requests which change state on the IdP are never retried
+func isSafeMethod(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	return false
+}
+
+// isTransient report if the request failed with an error or status which may not happen again
+func isTransient(resp *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return true
+		}
+
+		return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+			errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// retryBackoff the wait before the retry, doubling for each attempt up to the cap with jitter so concurrent logins
+// don't retry in step
+func retryBackoff(opts *HTTPClientOptions, attempt int) time.Duration {
+	wait := opts.RetryWaitMax
+	if attempt < 30 && opts.RetryWaitMin<<uint(attempt) < opts.RetryWaitMax {
+		wait = opts.RetryWaitMin << uint(attempt)
+	}
+
+	if wait <= 0 {
+		return 0
+	}
+
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
@@ -0,0 +1,24 @@
+package provider
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pkg/errors"
+)
+
+// ResumeSession request the sign on URL with the cookies of the client, returning the SAML response the IdP posts
+// back while its session is live, or an empty string when the IdP asks to login again
+func ResumeSession(client *HTTPClient, signOnURL string) (string, error) {
+	res, err := client.Get(signOnURL)
+	if err != nil {
+		return "", errors.Wrap(err, "error retrieving sign on page")
+	}
+
+	doc, err := goquery.NewDocumentFromResponse(res)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to build document from response")
+	}
+
+	samlResponse, _ := doc.Find(`input[name="SAMLResponse"]`).Attr("value")
+
+	return samlResponse, nil
+}
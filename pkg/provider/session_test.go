@@ -0,0 +1,45 @@
+package provider
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResumeSession(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "live"})
+			return
+		}
+
+		if _, err := r.Cookie("sid"); err != nil {
+			w.Write([]byte(`<form action="/login"><input name="username"/><input name="password" type="password"/></form>`))
+			return
+		}
+
+		w.Write([]byte(`<form action="https://signin.aws.amazon.com/saml"><input type="hidden" name="SAMLResponse" value="PHNhbWw+"/></form>`))
+	}))
+	defer ts.Close()
+
+	jar, err := NewCookieJar()
+	require.Nil(t, err)
+
+	hc, err := NewHTTPClient(NewDefaultTransport(false), &HTTPClientOptions{CookieJar: jar})
+	require.Nil(t, err)
+
+	// without a session the IdP asks to login
+	samlResponse, err := ResumeSession(hc, ts.URL+"/sso")
+	require.Nil(t, err)
+	require.Equal(t, "", samlResponse)
+
+	res, err := hc.Get(ts.URL + "/login")
+	require.Nil(t, err)
+	res.Body.Close()
+
+	samlResponse, err = ResumeSession(hc, ts.URL+"/sso")
+	require.Nil(t, err)
+	require.Equal(t, "PHNhbWw+", samlResponse)
+}
@@ -32,12 +32,18 @@ type Client struct {
 // New create a new Shibboleth client
 func New(idpAccount *cfg.IDPAccount) (*Client, error) {
 
+	tlsConfig, err := provider.NewTLSConfig(idpAccount)
+	if err != nil {
+		return nil, errors.Wrap(err, "error building tls config")
+	}
+	tlsConfig.Renegotiation = tls.RenegotiateFreelyAsClient
+
 	tr := &http.Transport{
 		Proxy:           http.ProxyFromEnvironment,
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: idpAccount.SkipVerify, Renegotiation: tls.RenegotiateFreelyAsClient},
+		TLSClientConfig: tlsConfig,
 	}
 
-	client, err := provider.NewHTTPClient(tr)
+	client, err := provider.NewHTTPClient(tr, provider.BuildHTTPClientOpts(idpAccount))
 	if err != nil {
 		return nil, errors.Wrap(err, "error building http client")
 	}
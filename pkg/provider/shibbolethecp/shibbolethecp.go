@@ -64,12 +64,18 @@ type authnRequestData struct {
 // New creates a new shibboleth-ecp client
 func New(idpAccount *cfg.IDPAccount) (*Client, error) {
 
+	tlsConfig, err := provider.NewTLSConfig(idpAccount)
+	if err != nil {
+		return nil, errors.Wrap(err, "error building tls config")
+	}
+	tlsConfig.Renegotiation = tls.RenegotiateFreelyAsClient
+
 	tr := &http.Transport{
 		Proxy:           http.ProxyFromEnvironment,
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: idpAccount.SkipVerify, Renegotiation: tls.RenegotiateFreelyAsClient},
+		TLSClientConfig: tlsConfig,
 	}
 
-	client, err := provider.NewHTTPClient(tr)
+	client, err := provider.NewHTTPClient(tr, provider.BuildHTTPClientOpts(idpAccount))
 	if err != nil {
 		return nil, errors.Wrap(err, "error building http client")
 	}
@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/versent/saml2aws/pkg/cfg"
+)
+
+// NewTransport configure a transport with the TLS settings of the idp account
+func NewTransport(account *cfg.IDPAccount) (*http.Transport, error) {
+	tlsConfig, err := NewTLSConfig(account)
+	if err != nil {
+		return nil, err
+	}
+
+	tr := NewDefaultTransport(account.SkipVerify)
+	tr.TLSClientConfig = tlsConfig
+
+	return tr, nil
+}
+
+// NewTLSConfig build the TLS settings of the idp account, trusting the certificates of ca_bundle along with the system
+// ones and presenting the client_cert and client_key pair to IdPs requiring mutual TLS
+func NewTLSConfig(account *cfg.IDPAccount) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: account.SkipVerify}
+
+	if account.CABundle != "" {
+		data, err := readPEMFile(account.CABundle)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read ca bundle")
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			// the system pool isn't available on every platform
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, errors.Errorf("no certificates found in ca bundle %s", account.CABundle)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if account.ClientCert != "" || account.ClientKey != "" {
+		if account.ClientCert == "" || account.ClientKey == "" {
+			return nil, errors.New("client_cert and client_key must be set together")
+		}
+
+		certPEM, err := readPEMFile(account.ClientCert)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read client certificate")
+		}
+
+		keyPEM, err := readPEMFile(account.ClientKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read client key")
+		}
+
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load client certificate %s", account.ClientCert)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+func readPEMFile(filename string) ([]byte, error) {
+	filename, err := homedir.Expand(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadFile(filename)
+}
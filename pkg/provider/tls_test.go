@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/versent/saml2aws/pkg/cfg"
+)
+
+// writeTestCertificate write a self signed certificate and its key to PEM files in the directory
+func writeTestCertificate(t *testing.T, dir, name string) (string, string, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.Nil(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.Nil(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.Nil(t, err)
+
+	certFile := filepath.Join(dir, name+".pem")
+	keyFile := filepath.Join(dir, name+"-key.pem")
+
+	err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	require.Nil(t, err)
+	err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	require.Nil(t, err)
+
+	return certFile, keyFile, cert
+}
+
+func TestNewTransportCABundleAndClientCert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "saml2aws-tls")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	clientCert, clientKey, clientCA := writeTestCertificate(t, dir, "client")
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCA)
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	ts.StartTLS()
+	defer ts.Close()
+
+	caBundle := filepath.Join(dir, "ca.pem")
+	err = ioutil.WriteFile(caBundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0600)
+	require.Nil(t, err)
+
+	// the server certificate isn't trusted without the bundle
+	tr, err := NewTransport(&cfg.IDPAccount{})
+	require.Nil(t, err)
+	_, err = (&http.Client{Transport: tr}).Get(ts.URL)
+	require.Error(t, err)
+
+	// the server requires a client certificate
+	tr, err = NewTransport(&cfg.IDPAccount{CABundle: caBundle})
+	require.Nil(t, err)
+	_, err = (&http.Client{Transport: tr}).Get(ts.URL)
+	require.Error(t, err)
+
+	tr, err = NewTransport(&cfg.IDPAccount{CABundle: caBundle, ClientCert: clientCert, ClientKey: clientKey})
+	require.Nil(t, err)
+	res, err := (&http.Client{Transport: tr}).Get(ts.URL)
+	require.Nil(t, err)
+	res.Body.Close()
+	require.Equal(t, 200, res.StatusCode)
+}
+
+func TestNewTLSConfigErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "saml2aws-tls")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	clientCert, _, _ := writeTestCertificate(t, dir, "client")
+
+	_, err = NewTLSConfig(&cfg.IDPAccount{CABundle: filepath.Join(dir, "missing.pem")})
+	require.Error(t, err)
+
+	empty := filepath.Join(dir, "empty.pem")
+	require.Nil(t, ioutil.WriteFile(empty, []byte("not a certificate"), 0600))
+
+	_, err = NewTLSConfig(&cfg.IDPAccount{CABundle: empty})
+	require.Error(t, err)
+
+	_, err = NewTLSConfig(&cfg.IDPAccount{ClientCert: clientCert})
+	require.Error(t, err)
+
+	tlsConfig, err := NewTLSConfig(&cfg.IDPAccount{SkipVerify: true})
+	require.Nil(t, err)
+	require.True(t, tlsConfig.InsecureSkipVerify)
+	require.Nil(t, tlsConfig.RootCAs)
+}
@@ -0,0 +1,191 @@
+package samlcache
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/versent/saml2aws/helper/credentials"
+	"github.com/versent/saml2aws/pkg/atomicfile"
+)
+
+const (
+	// DefaultCacheDir the directory holding the cached SAML responses when stored on disk
+	DefaultCacheDir = "~/.saml2aws.d/cache"
+
+	// ExpiryMargin cached responses are treated as expired this long before the assertion expires
+	// to allow for clock skew and the time taken to call STS
+	ExpiryMargin = 30 * time.Second
+
+	// ModeFile cache SAML responses in files readable only by the current user
+	ModeFile = "file"
+
+	// ModeKeychain cache SAML responses through the credentials helper
+	ModeKeychain = "keychain"
+
+	// ModeNone disable caching of SAML responses
+	ModeNone = "none"
+
+	keychainURLPrefix = "saml2aws-assertion-cache://"
+)
+
+var (
+	// ErrNotFound returned when there is no cached SAML response for the idp account
+	ErrNotFound = errors.New("cached saml assertion not found")
+
+	logger = logrus.WithField("pkg", "samlcache")
+)
+
+// Entry a cached SAML response along with the IdP session it was issued for
+type Entry struct {
+	URL          string    `json:"url"`
+	Username     string    `json:"username"`
+	SAMLResponse string    `json:"samlResponse"`
+	NotOnOrAfter time.Time `json:"notOnOrAfter"`
+}
+
+// Valid returns true if the entry was issued for the same IdP and user and has not expired
+func (e *Entry) Valid(url, username string) bool {
+	if e.URL != url || e.Username != username || e.SAMLResponse == "" {
+		return false
+	}
+
+	return time.Now().Add(ExpiryMargin).Before(e.NotOnOrAfter)
+}
+
+// Store persists the cached SAML responses keyed by idp account name
+type Store interface {
+	Load(idpAccount string) (*Entry, error)
+	Save(idpAccount string, entry *Entry) error
+	Delete(idpAccount string) error
+}
+
+// NewStore build the store for the configured cache mode, a nil store is returned when caching is disabled
+func NewStore(mode string) (Store, error) {
+	switch mode {
+	case "", ModeFile:
+		return NewFileStore(DefaultCacheDir)
+	case ModeKeychain:
+		return &KeychainStore{}, nil
+	case ModeNone:
+		return nil, nil
+	}
+
+	return nil, errors.Errorf("unknown assertion cache %s", mode)
+}
+
+// FileStore caches SAML responses as files with 0600 permissions
+type FileStore struct {
+	Dir string
+}
+
+// NewFileStore build a file store in the directory, expanding the home directory
+func NewFileStore(dir string) (*FileStore, error) {
+	dir, err := homedir.Expand(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileStore{Dir: dir}, nil
+}
+
+// Load read the cached SAML response for the idp account
+func (fs *FileStore) Load(idpAccount string) (*Entry, error) {
+	data, err := ioutil.ReadFile(fs.filename(idpAccount))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrap(err, "unable to read cached saml assertion")
+	}
+
+	return decodeEntry(data)
+}
+
+// Save write the SAML response for the idp account, replacing any existing entry
+func (fs *FileStore) Save(idpAccount string, entry *Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	filename := fs.filename(idpAccount)
+
+	logger.WithField("filename", filename).Debug("saving saml assertion")
+
+	return atomicfile.WriteFile(filename, data)
+}
+
+// Delete remove the cached SAML response for the idp account
+func (fs *FileStore) Delete(idpAccount string) error {
+	err := os.Remove(fs.filename(idpAccount))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (fs *FileStore) filename(idpAccount string) string {
+	return filepath.Join(fs.Dir, filepath.Base(idpAccount)+".json")
+}
+
+// KeychainStore caches SAML responses through the configured credentials helper
+type KeychainStore struct{}
+
+// Load read the cached SAML response for the idp account
+func (ks *KeychainStore) Load(idpAccount string) (*Entry, error) {
+	_, secret, err := credentials.CurrentHelper.Get(keychainURLPrefix + idpAccount)
+	if err != nil {
+		if credentials.IsErrCredentialsNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrap(err, "unable to read cached saml assertion")
+	}
+
+	return decodeEntry([]byte(secret))
+}
+
+// Save write the SAML response for the idp account, replacing any existing entry
+func (ks *KeychainStore) Save(idpAccount string, entry *Entry) error {
+	if !credentials.SupportsStorage() {
+		return errors.New("no credentials helper available to cache the saml assertion")
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return credentials.CurrentHelper.Add(&credentials.Credentials{
+		ServerURL: keychainURLPrefix + idpAccount,
+		Username:  idpAccount,
+		Secret:    string(data),
+	})
+}
+
+// Delete remove the cached SAML response for the idp account
+func (ks *KeychainStore) Delete(idpAccount string) error {
+	err := credentials.CurrentHelper.Delete(keychainURLPrefix + idpAccount)
+	if err != nil && !credentials.IsErrCredentialsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func decodeEntry(data []byte) (*Entry, error) {
+	entry := new(Entry)
+
+	err := json.Unmarshal(data, entry)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decode cached saml assertion")
+	}
+
+	return entry, nil
+}
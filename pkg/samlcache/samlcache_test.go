@@ -0,0 +1,126 @@
+package samlcache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/versent/saml2aws/helper/credentials"
+)
+
+func TestEntryValid(t *testing.T) {
+	entry := &Entry{
+		URL:          "https://id.example.com",
+		Username:     "wolfeidau",
+		SAMLResponse: "PHNhbWxwOlJlc3BvbnNlLz4=",
+		NotOnOrAfter: time.Now().Add(5 * time.Minute),
+	}
+
+	assert.True(t, entry.Valid("https://id.example.com", "wolfeidau"))
+	assert.False(t, entry.Valid("https://id.example.com", "someoneelse"))
+	assert.False(t, entry.Valid("https://id.other.com", "wolfeidau"))
+
+	entry.NotOnOrAfter = time.Now().Add(ExpiryMargin / 2)
+	assert.False(t, entry.Valid("https://id.example.com", "wolfeidau"))
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "samlcache")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileStore(filepath.Join(dir, "cache"))
+	require.Nil(t, err)
+
+	_, err = store.Load("default")
+	assert.Equal(t, ErrNotFound, err)
+
+	entry := &Entry{
+		URL:          "https://id.example.com",
+		Username:     "wolfeidau",
+		SAMLResponse: "PHNhbWxwOlJlc3BvbnNlLz4=",
+		NotOnOrAfter: time.Now().Add(5 * time.Minute).Round(time.Second),
+	}
+
+	err = store.Save("default", entry)
+	require.Nil(t, err)
+
+	info, err := os.Stat(filepath.Join(dir, "cache", "default.json"))
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := store.Load("default")
+	require.Nil(t, err)
+	assert.Equal(t, entry.SAMLResponse, loaded.SAMLResponse)
+	assert.True(t, entry.NotOnOrAfter.Equal(loaded.NotOnOrAfter))
+
+	require.Nil(t, store.Delete("default"))
+	require.Nil(t, store.Delete("default"))
+
+	_, err = store.Load("default")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+type memoryHelper struct {
+	secrets map[string]string
+}
+
+func (m *memoryHelper) Add(creds *credentials.Credentials) error {
+	m.secrets[creds.ServerURL] = creds.Secret
+	return nil
+}
+
+func (m *memoryHelper) Delete(serverURL string) error {
+	delete(m.secrets, serverURL)
+	return nil
+}
+
+func (m *memoryHelper) Get(serverURL string) (string, string, error) {
+	secret, ok := m.secrets[serverURL]
+	if !ok {
+		return "", "", credentials.ErrCredentialsNotFound
+	}
+	return "", secret, nil
+}
+
+func (m *memoryHelper) SupportsCredentialStorage() bool {
+	return true
+}
+
+func TestKeychainStore(t *testing.T) {
+	previous := credentials.CurrentHelper
+	defer func() { credentials.CurrentHelper = previous }()
+
+	credentials.CurrentHelper = &memoryHelper{secrets: map[string]string{}}
+
+	store, err := NewStore(ModeKeychain)
+	require.Nil(t, err)
+
+	_, err = store.Load("default")
+	assert.Equal(t, ErrNotFound, err)
+
+	err = store.Save("default", &Entry{SAMLResponse: "PHNhbWxwOlJlc3BvbnNlLz4="})
+	require.Nil(t, err)
+
+	loaded, err := store.Load("default")
+	require.Nil(t, err)
+	assert.Equal(t, "PHNhbWxwOlJlc3BvbnNlLz4=", loaded.SAMLResponse)
+
+	require.Nil(t, store.Delete("default"))
+
+	_, err = store.Load("default")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestNewStore(t *testing.T) {
+	store, err := NewStore(ModeNone)
+	assert.Nil(t, err)
+	assert.Nil(t, store)
+
+	_, err = NewStore("bogus")
+	assert.Error(t, err)
+}
@@ -23,5 +23,13 @@ func BuildEnvVars(awsCreds *awsconfig.AWSCredentials, account *cfg.IDPAccount, e
 		environmentVars = append(environmentVars, fmt.Sprintf("AWS_PROFILE=%s", account.Profile))
 		environmentVars = append(environmentVars, fmt.Sprintf("AWS_DEFAULT_PROFILE=%s", account.Profile))
 	}
+
+	// expose the session attributes supplied by the IdP so scripts can use them
+	if awsCreds.RoleSessionName != "" {
+		environmentVars = append(environmentVars, fmt.Sprintf("SAML2AWS_ROLE_SESSION_NAME=%s", awsCreds.RoleSessionName))
+	}
+	if awsCreds.SourceIdentity != "" {
+		environmentVars = append(environmentVars, fmt.Sprintf("SAML2AWS_SOURCE_IDENTITY=%s", awsCreds.SourceIdentity))
+	}
 	return environmentVars
 }
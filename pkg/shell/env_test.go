@@ -60,3 +60,33 @@ func TestBuildEnvVars(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildEnvVarsWithSessionAttributes(t *testing.T) {
+	account := &cfg.IDPAccount{
+		Profile: "saml",
+	}
+	awsCreds := &awsconfig.AWSCredentials{
+		AWSAccessKey:     "123",
+		AWSSecretKey:     "345",
+		AWSSecurityToken: "567",
+		AWSSessionToken:  "567",
+		RoleSessionName:  "wolfeidau@example.com",
+		SourceIdentity:   "wolfeidau",
+	}
+
+	want := []string{
+		"AWS_SESSION_TOKEN=567",
+		"AWS_SECURITY_TOKEN=567",
+		"EC2_SECURITY_TOKEN=567",
+		"AWS_ACCESS_KEY_ID=123",
+		"AWS_SECRET_ACCESS_KEY=345",
+		"AWS_PROFILE=saml",
+		"AWS_DEFAULT_PROFILE=saml",
+		"SAML2AWS_ROLE_SESSION_NAME=wolfeidau@example.com",
+		"SAML2AWS_SOURCE_IDENTITY=wolfeidau",
+	}
+
+	if got := BuildEnvVars(awsCreds, account, &flags.LoginExecFlags{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildEnvVars() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,70 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// forwardedSignals the signals passed on to the child rather than ending saml2aws
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP}
+
+// ExecCmd exec the command directly without a shell, so the arguments reach it unchanged, forwarding signals to it
+func ExecCmd(cmdline []string, envVars []string) error {
+
+	if len(cmdline) < 1 {
+		return errors.New("command to execute required")
+	}
+
+	cmd := exec.Command(cmdline[0], cmdline[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), envVars...)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				if err := cmd.Process.Signal(sig); err != nil {
+					logrus.WithError(err).WithField("signal", sig).Debug("unable to forward signal")
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return cmd.Wait()
+}
+
+// ExitCode return the exit code of the child if it is the cause of the error, a child killed by a signal exits with
+// 128 plus the signal number like it would in a shell
+func ExitCode(err error) (int, bool) {
+	exitErr, ok := errors.Cause(err).(*exec.ExitError)
+	if !ok {
+		return 0, false
+	}
+
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal()), true
+	}
+
+	return exitErr.ExitCode(), true
+}
@@ -12,3 +12,19 @@ func TestExecShellCmd(t *testing.T) {
 	assert.Nil(t, err)
 
 }
+
+func TestExecCmd(t *testing.T) {
+
+	err := ExecCmd([]string{"/bin/sh", "-c", `test "$1" = "a 'b' c" && test "$TESTTEST" = 123`, "sh", "a 'b' c"}, []string{"TESTTEST=123"})
+	assert.Nil(t, err)
+
+	err = ExecCmd([]string{"/bin/sh", "-c", "exit 3"}, nil)
+	code, ok := ExitCode(err)
+	assert.True(t, ok)
+	assert.Equal(t, 3, code)
+
+	err = ExecCmd([]string{"/bin/sh", "-c", "kill -TERM $$"}, nil)
+	code, ok = ExitCode(err)
+	assert.True(t, ok)
+	assert.Equal(t, 143, code)
+}
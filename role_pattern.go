@@ -0,0 +1,115 @@
+package saml2aws
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RegexpRolePrefix marks a role pattern as a regular expression matched against the role ARN
+const RegexpRolePrefix = "re:"
+
+// AmbiguousRoleError returned when a role pattern matches more than one role
+type AmbiguousRoleError struct {
+	Pattern    string
+	Candidates []*AWSRole
+}
+
+func (e *AmbiguousRoleError) Error() string {
+	lines := []string{fmt.Sprintf("role %s is ambiguous, it matches %d roles:", e.Pattern, len(e.Candidates))}
+	for _, role := range e.Candidates {
+		lines = append(lines, "  "+role.RoleARN)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// IsAccountRolePattern returns true if the pattern names a role as account/role, where the account is an id or alias
+func IsAccountRolePattern(pattern string) bool {
+	return !strings.HasPrefix(pattern, RegexpRolePrefix) && !strings.Contains(pattern, ":") && strings.Contains(pattern, "/")
+}
+
+// MatchRoles locate the roles whose ARN matches the pattern, which is either a role ARN, a glob where * matches any run
+// of characters and ? any single character, or a regular expression prefixed with re:
+func MatchRoles(awsRoles []*AWSRole, pattern string) ([]*AWSRole, error) {
+	matcher, err := roleRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := []*AWSRole{}
+	for _, awsRole := range awsRoles {
+		if matcher.MatchString(awsRole.RoleARN) {
+			roles = append(roles, awsRole)
+		}
+	}
+
+	return roles, nil
+}
+
+// MatchAccountRoles locate the roles matching an account/role pattern such as prod/Admin, the account is matched
+// against the id and alias of each account and the role against the role name, either may be a glob
+func MatchAccountRoles(awsAccounts []*AWSAccount, pattern string) ([]*AWSRole, error) {
+	tokens := strings.SplitN(pattern, "/", 2)
+	if len(tokens) != 2 {
+		return nil, errors.Errorf("invalid account role pattern %s, expected account/role", pattern)
+	}
+
+	accountMatcher := globRegexp(tokens[0])
+	roleMatcher := globRegexp(tokens[1])
+
+	roles := []*AWSRole{}
+	for _, awsAccount := range awsAccounts {
+		if !accountMatcher.MatchString(awsAccount.ID) && (awsAccount.Alias == "" || !accountMatcher.MatchString(awsAccount.Alias)) {
+			continue
+		}
+
+		for _, awsRole := range awsAccount.Roles {
+			name := awsRole.Name
+			if name == "" {
+				name = RoleNameFromARN(awsRole.RoleARN)
+			}
+
+			if roleMatcher.MatchString(name) {
+				roles = append(roles, awsRole)
+			}
+		}
+	}
+
+	return roles, nil
+}
+
+// ExpectOneRole return the only role matched by a pattern, or an error naming the candidates if there are several
+func ExpectOneRole(roles []*AWSRole, pattern string) (*AWSRole, error) {
+	switch len(roles) {
+	case 0:
+		return nil, fmt.Errorf("Supplied role not found in saml assertion: %s", pattern)
+	case 1:
+		return roles[0], nil
+	}
+
+	return nil, &AmbiguousRoleError{Pattern: pattern, Candidates: roles}
+}
+
+func roleRegexp(pattern string) (*regexp.Regexp, error) {
+	if !strings.HasPrefix(pattern, RegexpRolePrefix) {
+		return globRegexp(pattern), nil
+	}
+
+	matcher, err := regexp.Compile(strings.TrimPrefix(pattern, RegexpRolePrefix))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid role regular expression %s", pattern)
+	}
+
+	return matcher, nil
+}
+
+func globRegexp(pattern string) *regexp.Regexp {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.Replace(expr, `\*`, ".*", -1)
+	expr = strings.Replace(expr, `\?`, ".", -1)
+
+	return regexp.MustCompile("^" + expr + "$")
+}
@@ -0,0 +1,82 @@
+package saml2aws
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPatternRoles() []*AWSRole {
+	return []*AWSRole{
+		{RoleARN: "arn:aws:iam::000000000001:role/Development"},
+		{RoleARN: "arn:aws:iam::000000000001:role/ReadOnly"},
+		{RoleARN: "arn:aws:iam::000000000002:role/ReadOnly"},
+	}
+}
+
+func TestMatchRoles(t *testing.T) {
+	awsRoles := testPatternRoles()
+
+	roles, err := MatchRoles(awsRoles, "arn:aws:iam::000000000001:role/ReadOnly")
+	require.Nil(t, err)
+	assert.Equal(t, []*AWSRole{awsRoles[1]}, roles)
+
+	roles, err = MatchRoles(awsRoles, "*:role/ReadOnly")
+	require.Nil(t, err)
+	assert.Equal(t, []*AWSRole{awsRoles[1], awsRoles[2]}, roles)
+
+	roles, err = MatchRoles(awsRoles, "arn:aws:iam::00000000000?:role/Dev*")
+	require.Nil(t, err)
+	assert.Equal(t, []*AWSRole{awsRoles[0]}, roles)
+
+	roles, err = MatchRoles(awsRoles, "re:000000000002:role/(ReadOnly|Admin)$")
+	require.Nil(t, err)
+	assert.Equal(t, []*AWSRole{awsRoles[2]}, roles)
+
+	roles, err = MatchRoles(awsRoles, "*:role/Admin")
+	require.Nil(t, err)
+	assert.Empty(t, roles)
+
+	_, err = MatchRoles(awsRoles, "re:(")
+	assert.Error(t, err)
+}
+
+func TestMatchAccountRoles(t *testing.T) {
+	awsRoles := testPatternRoles()
+	awsAccounts := []*AWSAccount{
+		{ID: "000000000001", Alias: "production", Roles: awsRoles[:2]},
+		{ID: "000000000002", Roles: awsRoles[2:]},
+	}
+
+	assert.True(t, IsAccountRolePattern("production/ReadOnly"))
+	assert.False(t, IsAccountRolePattern("*:role/ReadOnly"))
+	assert.False(t, IsAccountRolePattern("re:role/ReadOnly"))
+
+	roles, err := MatchAccountRoles(awsAccounts, "production/ReadOnly")
+	require.Nil(t, err)
+	assert.Equal(t, []*AWSRole{awsRoles[1]}, roles)
+
+	roles, err = MatchAccountRoles(awsAccounts, "000000000002/Read*")
+	require.Nil(t, err)
+	assert.Equal(t, []*AWSRole{awsRoles[2]}, roles)
+
+	roles, err = MatchAccountRoles(awsAccounts, "*/ReadOnly")
+	require.Nil(t, err)
+	assert.Equal(t, []*AWSRole{awsRoles[1], awsRoles[2]}, roles)
+}
+
+func TestExpectOneRole(t *testing.T) {
+	awsRoles := testPatternRoles()
+
+	role, err := ExpectOneRole(awsRoles[:1], "*:role/Development")
+	require.Nil(t, err)
+	assert.Equal(t, awsRoles[0], role)
+
+	_, err = ExpectOneRole(nil, "*:role/Admin")
+	assert.Error(t, err)
+
+	_, err = ExpectOneRole(awsRoles[1:], "*:role/ReadOnly")
+	require.IsType(t, &AmbiguousRoleError{}, err)
+	assert.Contains(t, err.Error(), "arn:aws:iam::000000000002:role/ReadOnly")
+}
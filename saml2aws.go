@@ -86,6 +86,12 @@ type SAMLClient interface {
 	Authenticate(loginDetails *creds.LoginDetails) (string, error)
 }
 
+// SessionResumer implemented by the SAML clients which can return a SAML response from a live IdP session held in the
+// cookies of their idp account, without the password or MFA, an empty response means the IdP wants a new login
+type SessionResumer interface {
+	ResumeSession(loginDetails *creds.LoginDetails) (string, error)
+}
+
 // NewSAMLClient create a new SAML client
 func NewSAMLClient(idpAccount *cfg.IDPAccount) (SAMLClient, error) {
 	switch idpAccount.Provider {
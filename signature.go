@@ -0,0 +1,243 @@
+package saml2aws
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"strings"
+
+	"github.com/beevik/etree"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+	"github.com/versent/saml2aws/pkg/cfg"
+)
+
+const (
+	responseTag  = "Response"
+	signatureTag = "Signature"
+)
+
+var (
+	// ErrUnsignedAssertion returned when signature verification is enabled but neither
+	// the SAML response nor the assertion carry a signature
+	ErrUnsignedAssertion = errors.New("saml assertion is not signed, refusing to trust it")
+
+	// ErrMultipleAssertions returned when a SAML response contains more than one assertion,
+	// this is a common marker of a signature wrapping attack
+	ErrMultipleAssertions = errors.New("saml response contains more than one assertion")
+)
+
+// SignatureVerificationEnabled returns true if the account has IdP certificates configured
+func SignatureVerificationEnabled(idpAccount *cfg.IDPAccount) bool {
+	return idpAccount.IDPCertificate != "" || idpAccount.IDPMetadataFile != ""
+}
+
+// LoadIDPCertificates load the trusted IdP signing certificates from the PEM file and/or
+// the SAML metadata file configured on the account
+func LoadIDPCertificates(idpAccount *cfg.IDPAccount) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+
+	if idpAccount.IDPCertificate != "" {
+		data, err := readConfigFile(idpAccount.IDPCertificate)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read idp certificate")
+		}
+
+		pemCerts, err := ParsePEMCertificates(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse idp certificate %s", idpAccount.IDPCertificate)
+		}
+
+		certs = append(certs, pemCerts...)
+	}
+
+	if idpAccount.IDPMetadataFile != "" {
+		data, err := readConfigFile(idpAccount.IDPMetadataFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read idp metadata")
+		}
+
+		metadataCerts, err := ExtractMetadataCertificates(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse idp metadata %s", idpAccount.IDPMetadataFile)
+		}
+
+		certs = append(certs, metadataCerts...)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no idp certificates found in configuration")
+	}
+
+	return certs, nil
+}
+
+// ParsePEMCertificates parse all the certificates in a PEM encoded bundle
+func ParsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM encoded certificates found")
+	}
+
+	return certs, nil
+}
+
+// ExtractMetadataCertificates extract the signing certificates from an IdP SAML metadata document
+func ExtractMetadataCertificates(data []byte) ([]*x509.Certificate, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return nil, err
+	}
+
+	certs := []*x509.Certificate{}
+
+	for _, keyDescriptor := range doc.FindElements("//IDPSSODescriptor/KeyDescriptor") {
+		// key descriptors without a use attribute are valid for both signing and encryption
+		if use := keyDescriptor.SelectAttrValue("use", "signing"); use != "signing" {
+			continue
+		}
+
+		for _, certElement := range keyDescriptor.FindElements(".//X509Certificate") {
+			certData, err := base64.StdEncoding.DecodeString(stripWhitespace(certElement.Text()))
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to decode metadata certificate")
+			}
+
+			cert, err := x509.ParseCertificate(certData)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to parse metadata certificate")
+			}
+
+			certs = append(certs, cert)
+		}
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no signing certificates found in metadata")
+	}
+
+	return certs, nil
+}
+
+// VerifyAssertionSignature validates the XML-DSig signature on the SAML response and/or the assertion
+// against the trusted certificates. It returns a document containing only the signed content which should be
+// used for any further processing in place of the original data. An encrypted assertion is decrypted with the
+// supplied key, after the response signature has been checked as that covers the encrypted form.
+func VerifyAssertionSignature(data []byte, certs []*x509.Certificate, key *rsa.PrivateKey) ([]byte, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return nil, err
+	}
+
+	root := doc.Root()
+	if root == nil {
+		return nil, ErrMissingElement{Tag: responseTag}
+	}
+
+	if len(doc.FindElements("//"+assertionTag))+len(doc.FindElements("//"+encryptedAssertionTag)) > 1 {
+		return nil, ErrMultipleAssertions
+	}
+
+	ctx := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: certs})
+
+	signed := root
+
+	// when the response is signed the whole document, including the assertion, is covered
+	if root.Tag == responseTag && hasSignature(root) {
+		validated, err := ctx.Validate(root)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to verify saml response signature")
+		}
+
+		signed = validated
+	}
+
+	if err := decryptAssertions(signed, key); err != nil {
+		return nil, err
+	}
+
+	assertionElement := signed.FindElement(".//" + assertionTag)
+	if root.Tag == assertionTag {
+		assertionElement = signed
+	}
+	if assertionElement == nil {
+		return nil, ErrMissingAssertion
+	}
+
+	if hasSignature(assertionElement) {
+		// carry over the namespaces declared on the response so the assertion can be canonicalized on its own
+		detached, err := detachElement(assertionElement)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to detach saml assertion")
+		}
+
+		validated, err := ctx.Validate(detached)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to verify saml assertion signature")
+		}
+
+		signed = validated
+	} else if signed == root {
+		return nil, ErrUnsignedAssertion
+	}
+
+	verified := etree.NewDocument()
+	verified.SetRoot(signed)
+
+	return verified.WriteToBytes()
+}
+
+func hasSignature(el *etree.Element) bool {
+	for _, child := range el.ChildElements() {
+		if child.Tag == signatureTag {
+			return true
+		}
+	}
+	return false
+}
+
+func detachElement(el *etree.Element) (*etree.Element, error) {
+	nsCtx, err := etreeutils.NSBuildParentContext(el)
+	if err != nil {
+		return nil, err
+	}
+
+	return etreeutils.NSDetatch(nsCtx, el)
+}
+
+func readConfigFile(filename string) ([]byte, error) {
+	filename, err := homedir.Expand(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadFile(filename)
+}
+
+func stripWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
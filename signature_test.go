@@ -0,0 +1,207 @@
+package saml2aws
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/stretchr/testify/require"
+)
+
+type testKeyStore struct {
+	key  *rsa.PrivateKey
+	cert []byte
+}
+
+func (ks *testKeyStore) GetKeyPair() (*rsa.PrivateKey, []byte, error) {
+	return ks.key, ks.cert, nil
+}
+
+func newTestKeyStore(t *testing.T) (*testKeyStore, *x509.Certificate) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "id.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.Nil(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.Nil(t, err)
+
+	return &testKeyStore{key: key, cert: der}, cert
+}
+
+// signTestResponse loads the assertion fixture, drops its placeholder signature and signs the assertion
+// and optionally the response with the supplied key store.
+func signTestResponse(t *testing.T, ks dsig.X509KeyStore, signResponse bool) []byte {
+	data, err := ioutil.ReadFile("testdata/assertion.xml")
+	require.Nil(t, err)
+
+	doc := etree.NewDocument()
+	require.Nil(t, doc.ReadFromBytes(data))
+
+	response := doc.Root()
+	// the fixture uses the saml2 prefix without declaring it
+	response.CreateAttr("xmlns:saml2", "urn:oasis:names:tc:SAML:2.0:assertion")
+
+	assertion := response.FindElement("./Assertion")
+	assertion.RemoveChild(assertion.FindElement("./Signature"))
+
+	ctx := dsig.NewDefaultSigningContext(ks)
+	ctx.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+
+	detached, err := detachElement(assertion)
+	require.Nil(t, err)
+
+	signEnvelopedAfterIssuer(t, ctx, detached)
+
+	response.RemoveChild(assertion)
+	response.AddChild(detached)
+
+	if signResponse {
+		signEnvelopedAfterIssuer(t, ctx, response)
+	}
+
+	data, err = doc.WriteToBytes()
+	require.Nil(t, err)
+
+	return data
+}
+
+// signEnvelopedAfterIssuer adds an enveloped signature where the SAML schema expects it
+func signEnvelopedAfterIssuer(t *testing.T, ctx *dsig.SigningContext, el *etree.Element) {
+	sig, err := ctx.ConstructSignature(el, true)
+	require.Nil(t, err)
+
+	el.InsertChild(el.ChildElements()[1], sig)
+}
+
+func TestVerifyAssertionSignature(t *testing.T) {
+	ks, cert := newTestKeyStore(t)
+
+	data := signTestResponse(t, ks, false)
+
+	verified, err := VerifyAssertionSignature(data, []*x509.Certificate{cert}, nil)
+	require.Nil(t, err)
+
+	roles, err := ExtractAwsRoles(verified)
+	require.Nil(t, err)
+	require.Len(t, roles, 2)
+
+	duration, err := ExtractSessionDuration(verified)
+	require.Nil(t, err)
+	require.Equal(t, int64(28800), duration)
+}
+
+func TestVerifyAssertionSignatureSignedResponse(t *testing.T) {
+	ks, cert := newTestKeyStore(t)
+
+	data := signTestResponse(t, ks, true)
+
+	verified, err := VerifyAssertionSignature(data, []*x509.Certificate{cert}, nil)
+	require.Nil(t, err)
+
+	roles, err := ExtractAwsRoles(verified)
+	require.Nil(t, err)
+	require.Len(t, roles, 2)
+}
+
+func TestVerifyAssertionSignatureTampered(t *testing.T) {
+	ks, cert := newTestKeyStore(t)
+
+	data := signTestResponse(t, ks, false)
+	data = []byte(strings.Replace(string(data), "AWS-Admin-CloudOPSBuild", "AWS-Admin-CloudOPSProd", 1))
+
+	_, err := VerifyAssertionSignature(data, []*x509.Certificate{cert}, nil)
+	require.Error(t, err)
+}
+
+func TestVerifyAssertionSignatureUntrustedCertificate(t *testing.T) {
+	ks, _ := newTestKeyStore(t)
+	_, otherCert := newTestKeyStore(t)
+
+	data := signTestResponse(t, ks, false)
+
+	_, err := VerifyAssertionSignature(data, []*x509.Certificate{otherCert}, nil)
+	require.Error(t, err)
+}
+
+func TestVerifyAssertionSignatureUnsigned(t *testing.T) {
+	_, cert := newTestKeyStore(t)
+
+	data, err := ioutil.ReadFile("testdata/assertion_pingfed.xml")
+	require.Nil(t, err)
+
+	doc := etree.NewDocument()
+	require.Nil(t, doc.ReadFromBytes(data))
+	for _, sig := range doc.FindElements("//Signature") {
+		sig.Parent().RemoveChild(sig)
+	}
+	data, err = doc.WriteToBytes()
+	require.Nil(t, err)
+
+	_, err = VerifyAssertionSignature(data, []*x509.Certificate{cert}, nil)
+	require.Equal(t, ErrUnsignedAssertion, err)
+}
+
+func TestVerifyAssertionSignaturePlaceholder(t *testing.T) {
+	_, cert := newTestKeyStore(t)
+
+	data, err := ioutil.ReadFile("testdata/assertion.xml")
+	require.Nil(t, err)
+
+	_, err = VerifyAssertionSignature(data, []*x509.Certificate{cert}, nil)
+	require.Error(t, err)
+}
+
+func TestParsePEMCertificates(t *testing.T) {
+	ks, cert := newTestKeyStore(t)
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ks.cert})
+
+	certs, err := ParsePEMCertificates(data)
+	require.Nil(t, err)
+	require.Len(t, certs, 1)
+	require.True(t, cert.Equal(certs[0]))
+
+	_, err = ParsePEMCertificates([]byte("not a certificate"))
+	require.Error(t, err)
+}
+
+func TestExtractMetadataCertificates(t *testing.T) {
+	ks, cert := newTestKeyStore(t)
+
+	metadata := fmt.Sprintf(`<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="http://id.example.com/adfs/services/trust">
+  <md:IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:KeyDescriptor use="encryption">
+      <ds:KeyInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><ds:X509Data><ds:X509Certificate>invalid</ds:X509Certificate></ds:X509Data></ds:KeyInfo>
+    </md:KeyDescriptor>
+    <md:KeyDescriptor use="signing">
+      <ds:KeyInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><ds:X509Data><ds:X509Certificate>%s</ds:X509Certificate></ds:X509Data></ds:KeyInfo>
+    </md:KeyDescriptor>
+  </md:IDPSSODescriptor>
+</md:EntityDescriptor>`, base64.StdEncoding.EncodeToString(ks.cert))
+
+	certs, err := ExtractMetadataCertificates([]byte(metadata))
+	require.Nil(t, err)
+	require.Len(t, certs, 1)
+	require.True(t, cert.Equal(certs[0]))
+}